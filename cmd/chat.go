@@ -9,6 +9,8 @@ import (
 
 	"github.com/amansingh-afk/qry/internal/backend"
 	"github.com/amansingh-afk/qry/internal/prompt"
+	"github.com/amansingh-afk/qry/internal/security"
+	"github.com/amansingh-afk/qry/internal/session"
 	"github.com/amansingh-afk/qry/internal/tui"
 	"github.com/amansingh-afk/qry/internal/ui"
 	tea "github.com/charmbracelet/bubbletea"
@@ -32,43 +34,109 @@ func runChat(cmd *cobra.Command, args []string) {
 	sessionID := getSession(b.Name())
 
 	// Create query function that the TUI will call
-	queryFunc := func(ctx context.Context, query string) (tui.QueryResult, error) {
+	queryFunc := func(ctx context.Context, query, model string, progress tui.ProgressFunc) (tui.QueryResult, error) {
+		ctx, cancel := context.WithTimeout(ctx, getTimeout(b.Name()))
+		defer cancel()
+
+		// Pick up .qry.yaml and template edits made since the last turn
+		prompt.Refresh(workDir)
+		defer prompt.ReadConfig()()
+
 		opts := backend.Options{
 			Model:     model,
 			Dialect:   dialect,
 			SessionID: sessionID,
 		}
 
-		// Use full prompt for new sessions, minimal prompt for existing sessions
+		// Use full prompt for new sessions, minimal prompt for existing
+		// sessions, and the full prompt again if the rules changed since
 		var sqlPrompt string
-		if sessionID == "" {
+		switch {
+		case sessionID == "":
 			sqlPrompt = prompt.BuildSQL(query, dialect)
-		} else {
+		case session.RulesChanged(workDir, sessionID, prompt.RulesHash()):
+			progress(tui.Progress{Activity: "re-sending changed rules"})
+			sqlPrompt = prompt.BuildRefresh(query, dialect)
+		default:
 			sqlPrompt = prompt.BuildFollowUp(query)
 		}
 
-		result, err := b.Query(ctx, sqlPrompt, workDir, opts)
+		// Stream partial text and agent activity into the TUI
+		var text strings.Builder
+		events := backend.Stream(ctx, b, sqlPrompt, workDir, opts)
+		result, err := backend.Collect(events, func(ev backend.Event) {
+			switch ev.Type {
+			case backend.EventText:
+				text.WriteString(ev.Text)
+				progress(tui.Progress{Text: text.String()})
+			case backend.EventActivity:
+				progress(tui.Progress{Activity: ev.Text})
+			}
+		})
 		if err != nil {
 			return tui.QueryResult{}, err
 		}
 
+		out := prompt.ExtractReply(result.Response)
+
+		var (
+			answer    *prompt.Answer
+			answerErr error
+		)
+		if prompt.Structured() && out.Kind != prompt.OutcomeRefusal && out.Kind != prompt.OutcomeClarification {
+			progress(tui.Progress{Activity: "checking answer format"})
+			a, err := prompt.ResolveAnswer(result.Response, backend.Repair(ctx, b, sqlPrompt, workDir, opts, getTimeout(b.Name()), &result))
+			if err != nil {
+				answerErr = err
+			} else {
+				answer, out = &a, a.Outcome()
+			}
+		}
+
 		// Update session for next query
 		if result.SessionID != "" {
 			sessionID = result.SessionID
-			saveSession(b.Name(), sessionID)
 		}
+		saveSession(b.Name(), result)
 
-		sql := prompt.ExtractSQL(result.Response)
-
-		return tui.QueryResult{
-			SQL:       sql,
-			SessionID: result.SessionID,
-		}, nil
+		qr := tui.QueryResult{
+			SQL:         out.SQL,
+			Outcome:     out,
+			SessionID:   result.SessionID,
+			Usage:       result.Usage,
+			Answer:      answer,
+			AnswerError: answerErr,
+		}
+		if s, err := session.Load(workDir); err == nil && s.SessionID == sessionID {
+			qr.SessionCostUSD = s.Usage.CostUSD
+		}
+		return qr, nil
 	}
 
 	// Create and run TUI
 	version := strings.TrimPrefix(ui.Version(), "qry ")
-	m := tui.NewModel(repo, b.Name(), model, version, workDir, queryFunc)
+	listModels := func(ctx context.Context) ([]string, error) {
+		return backend.ListModels(ctx, b)
+	}
+
+	// :explain runs outside the session, like qry explain
+	explain := func(ctx context.Context, sql, model string, progress tui.ProgressFunc) (string, error) {
+		ctx, cancel := context.WithTimeout(ctx, getTimeout(b.Name()))
+		defer cancel()
+
+		prompt.Refresh(workDir)
+		defer prompt.ReadConfig()()
+		explainPrompt := prompt.BuildExplain(sql, dialect, security.Validate(sql).Flags())
+		result, err := explainSQL(ctx, b, explainPrompt, backend.Options{Model: model, Dialect: dialect}, func(activity string) {
+			progress(tui.Progress{Activity: activity})
+		})
+		if err != nil {
+			return "", err
+		}
+		return result.Response, nil
+	}
+
+	m := tui.NewModel(repo, b.Name(), model, version, workDir, queryFunc, listModels, explain)
 	p := tea.NewProgram(m, tea.WithAltScreen())
 
 	if _, err := p.Run(); err != nil {
@@ -0,0 +1,420 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/amansingh-afk/qry/internal/backend"
+	"github.com/amansingh-afk/qry/internal/prompt"
+	"github.com/amansingh-afk/qry/internal/security"
+	"github.com/amansingh-afk/qry/internal/session"
+	"github.com/amansingh-afk/qry/internal/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	doctorAllFlag   bool
+	doctorQuickFlag bool
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check config, session state and backends",
+	Long: `Check that QRY is set up correctly in the current directory.
+Validates .qry.yaml, the .qry/ directory, session state and .gitignore,
+then checks each backend's version, auth and a trivial round trip.`,
+	Example: `  qry doctor
+  qry doctor --all      # every installed backend, not just the configured chain
+  qry doctor --quick    # skip the round trip query`,
+	Args: cobra.NoArgs,
+	Run:  runDoctor,
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorAllFlag, "all", false, "check every installed backend")
+	doctorCmd.Flags().BoolVar(&doctorQuickFlag, "quick", false, "skip the round trip query")
+}
+
+// doctor counts problems while printing them with a fix underneath
+type doctor struct {
+	problems int
+}
+
+// fail reports a problem that stops qry from working
+func (d *doctor) fail(fix, format string, args ...interface{}) {
+	d.problems++
+	ui.StepWarn("✗ "+format, args...)
+	if fix != "" {
+		ui.StepItem("  fix: %s", fix)
+	}
+}
+
+// warn reports something worth knowing that doesn't need fixing
+func (d *doctor) warn(fix, format string, args ...interface{}) {
+	ui.StepWarn("! "+format, args...)
+	if fix != "" {
+		ui.StepItem("  fix: %s", fix)
+	}
+}
+
+func runDoctor(cmd *cobra.Command, args []string) {
+	d := &doctor{}
+
+	ui.Header("Doctor")
+
+	d.checkConfig()
+	fmt.Println()
+	d.checkQryDir()
+	fmt.Println()
+	d.checkSession()
+	fmt.Println()
+	d.checkGitignore()
+	fmt.Println()
+	d.checkBackends()
+
+	if d.problems > 0 {
+		fmt.Println()
+		ui.Error("%d problem(s) found", d.problems)
+		fmt.Println()
+		os.Exit(1)
+	}
+	ui.Done("All checks passed")
+}
+
+func (d *doctor) checkConfig() {
+	ui.Step("Config")
+
+	if err := viper.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if errors.As(err, &notFound) {
+			d.warn("qry init", "No .qry.yaml, using defaults")
+		} else {
+			d.fail("fix the YAML syntax in .qry.yaml", "%s", firstLine(err.Error()))
+			return
+		}
+	} else {
+		ui.StepDone("%s", filepath.Base(viper.ConfigFileUsed()))
+	}
+
+	ok := true
+
+	if err := backend.LoadConfigured(); err != nil {
+		ok = false
+		for _, line := range strings.Split(err.Error(), "\n") {
+			d.fail("see the exec backend fields in the README", "%s", line)
+		}
+	}
+
+	name := viper.GetString("backend")
+	if _, err := backend.Get(name); err != nil {
+		ok = false
+		d.fail("set backend: to one of: "+strings.Join(backend.List(), ", "), "backend: %s", err)
+	}
+	for _, name := range viper.GetStringSlice("fallback") {
+		if _, err := backend.Get(name); err != nil {
+			ok = false
+			d.fail("remove it from fallback: or define it under backends:", "fallback: %s", err)
+		}
+	}
+
+	if t := viper.GetString("timeout"); t != "" {
+		if _, err := time.ParseDuration(t); err != nil {
+			ok = false
+			d.fail("use a duration like 2m or 90s", "timeout: invalid duration %q", t)
+		}
+	}
+	if ttl := viper.GetString("session.ttl"); ttl != "" {
+		if _, err := parseTTL(ttl); err != nil {
+			ok = false
+			d.fail("use a duration like 7d or 24h", "session.ttl: invalid duration %q", ttl)
+		}
+	}
+
+	if viper.IsSet("security.mode") {
+		mode := security.Mode(viper.GetString("security.mode"))
+		if mode != security.ModeStrict && mode != security.ModeWarn {
+			ok = false
+			d.fail("use strict or warn", "security.mode: unknown mode %q", mode)
+		}
+	}
+
+	sandboxMode := viper.GetString("sandbox.mode")
+	if mode, isString := viper.Get("sandbox").(string); isString {
+		sandboxMode = mode
+	}
+	if sandboxMode != "" && sandboxMode != backend.SandboxReadOnly && sandboxMode != backend.SandboxOff {
+		d.warn("use read-only or off", "sandbox: unknown mode %q, treated as read-only", sandboxMode)
+	}
+
+	for _, p := range backend.SchemaPaths() {
+		path := p
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workDir, p)
+		}
+		if _, err := os.Stat(path); err != nil {
+			d.warn("fix the path or remove it from schema_paths:", "schema_paths: %s not found", p)
+		}
+	}
+
+	if err := prompt.Load(workDir); err != nil {
+		ok = false
+		d.fail("fix the template in prompt: or "+prompt.PromptsDir+"/", "prompt: %s", firstLine(err.Error()))
+	}
+	if examples, err := prompt.LoadExamples(workDir); err != nil {
+		ok = false
+		d.fail("fix "+prompt.ExamplesFile+" or examples:", "%s", firstLine(err.Error()))
+	} else if len(examples) > 0 {
+		ui.StepItem("%d examples", len(examples))
+	}
+
+	if ok {
+		ui.StepItem("backend: %s, dialect: %s", name, getDialect())
+	}
+}
+
+func (d *doctor) checkQryDir() {
+	dir := session.DirPath(workDir)
+	ui.Step(".qry/")
+
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		ui.StepItem("Not created yet (created on first query)")
+		return
+	}
+	if err != nil {
+		d.fail("", "%s", err)
+		return
+	}
+	if !info.IsDir() {
+		d.fail("rm .qry && qry init", ".qry is a file, not a directory")
+		return
+	}
+
+	// Sessions and prompt files live here; nobody else should write to it
+	if info.Mode().Perm()&0022 != 0 {
+		d.fail("chmod go-w .qry", ".qry/ is writable by other users (%04o)", info.Mode().Perm())
+	}
+
+	f, err := os.CreateTemp(dir, "doctor-*")
+	if err != nil {
+		d.fail("chmod u+rwx .qry", ".qry/ is not writable: %s", err)
+		return
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	ui.StepDone("Writable (%04o)", info.Mode().Perm())
+}
+
+func (d *doctor) checkSession() {
+	ui.Step("Session")
+
+	s, err := session.Load(workDir)
+	if os.IsNotExist(err) {
+		ui.StepItem("No session (next query starts one)")
+		return
+	}
+	if err != nil {
+		d.fail("qry init --force", "Can't read %s: %s", session.Path(workDir), err)
+		return
+	}
+
+	current := viper.GetString("backend")
+	if backendFlag != "" {
+		current = strings.Split(backendFlag, ",")[0]
+	}
+
+	ttl := getSessionTTL()
+	age := time.Since(s.CreatedAt).Round(time.Minute)
+
+	switch {
+	case s.SessionID == "" && len(s.Totals) > 0:
+		ui.StepItem("No session, usage totals only (next query starts one)")
+	case s.SessionID == "":
+		d.warn("qry init --force", "Session file has no session ID")
+	case s.Backend != current:
+		ui.StepItem("Session belongs to %s, backend is %s (next query starts fresh)", s.Backend, current)
+	case ttl > 0 && age > ttl:
+		ui.StepItem("Expired after %s (next query starts fresh)", ttl)
+	default:
+		ui.StepDone("%s, %s old, %d queries", s.Backend, age, s.Usage.Queries)
+		if session.RulesChanged(workDir, s.SessionID, prompt.RulesHash()) {
+			ui.StepItem("Prompt or security rules changed since it started (next query sends them again)")
+		}
+	}
+}
+
+func (d *doctor) checkGitignore() {
+	ui.Step(".gitignore")
+
+	if _, err := os.Stat(filepath.Join(workDir, ".git")); err != nil {
+		ui.StepItem("Not a git repository")
+		return
+	}
+
+	content, err := os.ReadFile(filepath.Join(workDir, ".gitignore"))
+	if err != nil && !os.IsNotExist(err) {
+		d.fail("", "%s", err)
+		return
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		switch strings.TrimSpace(line) {
+		case ".qry/", ".qry", "/.qry/", "/.qry", ".qry/*", "/.qry/*":
+			ui.StepDone(".qry/ ignored")
+			return
+		}
+	}
+
+	// Sessions, prompt files and cassettes would otherwise get committed
+	d.fail("echo .qry/ >> .gitignore", ".qry/ is not in .gitignore")
+}
+
+func (d *doctor) checkBackends() {
+	ui.Step("Backends")
+
+	var backends []backend.Backend
+	if doctorAllFlag {
+		backends = backend.Available()
+		if len(backends) == 0 {
+			d.fail("qry init", "No backends installed")
+			return
+		}
+	} else {
+		chain, err := doctorChain()
+		if err != nil {
+			d.fail("", "%s", err)
+			return
+		}
+		backends = chain
+	}
+
+	for i, b := range backends {
+		model := getDefaultModel(b.Name())
+		if i == 0 && !doctorAllFlag {
+			model = getModel(b.Name())
+		}
+		d.checkBackend(b, model)
+	}
+}
+
+// doctorChain is the configured chain, without getBackendChain's
+// availability check so missing backends are reported individually
+func doctorChain() ([]backend.Backend, error) {
+	var names []string
+	if backendFlag != "" {
+		names = strings.Split(backendFlag, ",")
+	} else {
+		names = append([]string{viper.GetString("backend")}, viper.GetStringSlice("fallback")...)
+	}
+
+	var chain []backend.Backend
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		b, err := backend.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, b)
+	}
+	return chain, nil
+}
+
+func (d *doctor) checkBackend(b backend.Backend, model string) {
+	name := b.Name()
+
+	ctx, cancel := context.WithTimeout(context.Background(), getTimeout(name))
+	defer cancel()
+
+	if model != "" {
+		mctx, mcancel := context.WithTimeout(ctx, modelsTimeout)
+		models, err := backend.ListModels(mctx, b)
+		mcancel()
+		if err == nil && len(models) > 0 && !backend.MatchModel(models, model) {
+			d.fail("qry models "+name, "%s: unknown model %q", name, model)
+			return
+		}
+	}
+
+	var (
+		h   backend.Health
+		err error
+	)
+	if doctorQuickFlag {
+		h, err = quickHealth(ctx, b)
+	} else {
+		h, err = backend.CheckHealth(ctx, b, workDir, backend.Options{Model: model, Dialect: getDialect()})
+	}
+	if err != nil {
+		d.fail(healthFix(b, err), "%s", firstLine(err.Error()))
+		return
+	}
+
+	var details []string
+	if h.Version != "" {
+		details = append(details, h.Version)
+	}
+	if model != "" {
+		details = append(details, "model "+model)
+	}
+	if h.Detail != "" {
+		details = append(details, h.Detail)
+	}
+	if h.Latency > 0 {
+		details = append(details, fmt.Sprintf("round trip %s", h.Latency.Round(100*time.Millisecond)))
+	}
+
+	if len(details) > 0 {
+		ui.StepDone("%s (%s)", name, strings.Join(details, ", "))
+	} else {
+		ui.StepDone("%s", name)
+	}
+}
+
+// quickHealth runs only the backend's own checks, without a model call
+func quickHealth(ctx context.Context, b backend.Backend) (backend.Health, error) {
+	if !b.Available() {
+		return backend.Health{}, fmt.Errorf("%s not installed", b.Name())
+	}
+	if hc, ok := b.(backend.HealthChecker); ok {
+		return hc.Health(ctx)
+	}
+	return backend.Health{}, nil
+}
+
+// healthFix suggests what to do about a failed health check
+func healthFix(b backend.Backend, err error) string {
+	name := b.Name()
+
+	switch backend.KindOf(err) {
+	case backend.KindNotInstalled:
+		return b.InstallCmd()
+	case backend.KindAuth:
+		fix := fmt.Sprintf("log in with the %s CLI or set its API key", name)
+		if backend.SandboxFor(name).Enabled() {
+			fix += "; if the key is in an env var qry doesn't pass through, add it to sandbox.env"
+		}
+		return fix
+	case backend.KindRateLimit:
+		return "wait and retry, or add another backend under fallback:"
+	case backend.KindTimeout:
+		return fmt.Sprintf("raise backends.%s.timeout in .qry.yaml (now %s)", name, getTimeout(name))
+	case backend.KindMalformed:
+		return "update the CLI: " + b.InstallCmd()
+	}
+	if !b.Available() {
+		return b.InstallCmd()
+	}
+	return ""
+}
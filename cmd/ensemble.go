@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/amansingh-afk/qry/internal/backend"
+	"github.com/amansingh-afk/qry/internal/ensemble"
+	"github.com/amansingh-afk/qry/internal/guardrails"
+	"github.com/amansingh-afk/qry/internal/output"
+	"github.com/amansingh-afk/qry/internal/prompt"
+	"github.com/amansingh-afk/qry/internal/security"
+	"github.com/amansingh-afk/qry/internal/ui"
+)
+
+// runEnsemble sends the same prompt to several backends at once and
+// shows where their SQL agrees or differs
+func runEnsemble(query string, names []string) {
+	// Each backend uses its own default; one -m can't fit them all
+	if modelFlag != "" {
+		ui.Error("-m doesn't apply to --ensemble; set backends.<name>.model instead")
+		os.Exit(1)
+	}
+
+	var backends []backend.Backend
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		b, err := backend.Get(name)
+		if err != nil {
+			ui.Error("%s", err.Error())
+			os.Exit(1)
+		}
+
+		if !b.Available() {
+			ui.Warning("%s not installed, skipping", b.Name())
+			continue
+		}
+		backends = append(backends, b)
+	}
+
+	if len(backends) < 2 {
+		ui.Error("ensemble needs at least two available backends")
+		os.Exit(1)
+	}
+
+	dialect := getDialect()
+
+	// Always a fresh first-turn prompt: answers must be independent and
+	// must not clobber the shared session
+	sqlPrompt := prompt.BuildSQL(query, dialect)
+
+	if dryRunFlag {
+		ui.Info("Prompt:")
+		fmt.Println(sqlPrompt)
+		return
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	sec := security.Get()
+
+	ui.Thinking(strings.Join(backendNames(backends), ", "))
+
+	candidates := ensemble.Run(ctx, backends, func(ctx context.Context, b backend.Backend) (string, string, error) {
+		ctx, cancel := context.WithTimeout(ctx, getTimeout(b.Name()))
+		defer cancel()
+
+		model := getDefaultModel(b.Name())
+
+		result, err := b.Query(ctx, sqlPrompt, workDir, backend.Options{
+			Model:   model,
+			Dialect: dialect,
+		})
+		if err != nil {
+			return "", model, err
+		}
+
+		out := prompt.ExtractReply(result.Response)
+		if out.Kind != prompt.OutcomeSQL {
+			return "", model, fmt.Errorf("%s: %s", out.Kind, firstLine(out.Message))
+		}
+		sql := out.SQL
+
+		if secResult := security.Validate(sql); sec.IsBlocked(secResult) {
+			return "", model, fmt.Errorf("blocked: %s", secResult.Summary())
+		}
+
+		return sql, model, nil
+	})
+
+	ui.ClearLine()
+
+	report := ensemble.Compare(candidates)
+
+	if len(report.Groups) == 0 {
+		ui.Error("no backend returned SQL")
+		for _, c := range report.Candidates {
+			fmt.Fprintf(os.Stderr, "  %s: %s\n", c.Backend, c.Error)
+		}
+		os.Exit(1)
+	}
+
+	if !report.Compared() {
+		ui.Warning("Only one backend returned SQL; nothing to compare")
+	}
+
+	for _, g := range report.Groups {
+		if secResult := security.Validate(g.SQL); sec.ShouldWarn(secResult) {
+			ui.Warning("Security warning (%s): query references restricted data", strings.Join(g.Backends, ", "))
+		}
+		if warning := guardrails.Check(g.SQL); warning != "" {
+			ui.Warning("%s (%s)", warning, strings.Join(g.Backends, ", "))
+		}
+	}
+
+	if jsonFlag {
+		output.EnsembleJSON(os.Stdout, report, dialect)
+	} else {
+		output.EnsemblePretty(os.Stdout, report)
+	}
+}
+
+func backendNames(backends []backend.Backend) []string {
+	names := make([]string, 0, len(backends))
+	for _, b := range backends {
+		names = append(names, b.Name())
+	}
+	return names
+}
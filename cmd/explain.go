@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/amansingh-afk/qry/internal/backend"
+	"github.com/amansingh-afk/qry/internal/output"
+	"github.com/amansingh-afk/qry/internal/prompt"
+	"github.com/amansingh-afk/qry/internal/security"
+	"github.com/amansingh-afk/qry/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain [sql]",
+	Short: "Explain SQL in plain English",
+	Example: `  qry explain "SELECT u.email FROM users u JOIN orders o ON o.user_id = u.id"
+  qry explain < report.sql
+  pbpaste | qry explain --json`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runExplain(args)
+	},
+}
+
+func init() {
+	explainCmd.Flags().BoolVar(&jsonFlag, "json", false, "output JSON")
+	explainCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "show prompt without running")
+}
+
+func runExplain(args []string) {
+	sql, err := readSQLArg(args)
+	if err != nil {
+		ui.Error("%s", err.Error())
+		os.Exit(1)
+	}
+
+	chain, err := getBackendChain()
+	if err != nil {
+		ui.Error("%s", err.Error())
+		os.Exit(1)
+	}
+
+	dialect := getDialect()
+
+	// Excluded objects are flagged whatever the mode: the SQL already exists
+	secResult := security.Validate(sql)
+	explainPrompt := prompt.BuildExplain(sql, dialect, secResult.Flags())
+
+	if dryRunFlag {
+		ui.Info("Prompt:")
+		fmt.Println(explainPrompt)
+		return
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	var model string
+
+	attempt := func(ctx context.Context, b backend.Backend) (backend.Result, error) {
+		model = getModel(b.Name())
+		if b != chain[0] {
+			model = getDefaultModel(b.Name())
+		}
+
+		set, stop := ui.Activity(b.Name())
+		defer stop()
+
+		return explainSQL(ctx, b, explainPrompt, backend.Options{Model: model, Dialect: dialect}, set)
+	}
+
+	onFail := func(f backend.Failure) {
+		if f.Retrying {
+			ui.Warning("%s: %s, retrying", f.Backend, f.Kind)
+			return
+		}
+		if len(chain) > 1 {
+			ui.Warning("%s failed (%s)", f.Backend, f.Kind)
+		}
+	}
+
+	b, result, err := backend.Fallback(ctx, chain, getRetry(), attempt, onFail)
+	if err != nil {
+		ui.Error("%s", err.Error())
+		os.Exit(1)
+	}
+
+	// The explanation can name excluded objects the SQL only implies
+	flags := secResult.Merge(security.ScanText(result.Response)).Flags()
+	if len(flags) > 0 {
+		ui.Warning("The SQL or its explanation mentions objects excluded by security rules:")
+		for _, f := range flags {
+			fmt.Fprintln(os.Stderr, "  - "+f)
+		}
+	}
+
+	if jsonFlag {
+		output.ExplainJSON(os.Stdout, output.Explanation{
+			Explanation:   strings.TrimSpace(result.Response),
+			Backend:       b.Name(),
+			Model:         model,
+			Dialect:       dialect,
+			SecurityFlags: flags,
+			Usage:         usagePtr(result.Usage),
+		})
+	} else {
+		output.ExplainPretty(os.Stdout, result.Response, b.Name(), model, result.Usage)
+	}
+}
+
+// readSQLArg returns the SQL argument, or stdin when there's none or it's "-"
+func readSQLArg(args []string) (string, error) {
+	if len(args) == 1 && args[0] != "-" {
+		return strings.TrimSpace(args[0]), nil
+	}
+
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		return "", fmt.Errorf("pass SQL as an argument or on stdin")
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	sql := strings.TrimSpace(string(data))
+	if sql == "" {
+		return "", fmt.Errorf("no SQL on stdin")
+	}
+	return sql, nil
+}
+
+// explainSQL sends an explain or translate prompt outside the shared
+// session, so the session's instructions aren't muddied. onActivity
+// receives what the agent is doing.
+func explainSQL(ctx context.Context, b backend.Backend, explainPrompt string, opts backend.Options, onActivity func(string)) (backend.Result, error) {
+	opts.SessionID = ""
+	events := backend.Stream(ctx, b, explainPrompt, workDir, opts)
+	return backend.Collect(events, func(ev backend.Event) {
+		if ev.Type == backend.EventActivity {
+			onActivity(ev.Text)
+		}
+	})
+}
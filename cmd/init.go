@@ -1,12 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
 
 	"github.com/amansingh-afk/qry/internal/backend"
+	"github.com/amansingh-afk/qry/internal/prompt"
 	"github.com/amansingh-afk/qry/internal/session"
 	"github.com/amansingh-afk/qry/internal/ui"
 	"github.com/spf13/cobra"
@@ -44,6 +44,8 @@ var defaultModels = map[string]string{
 	"claude": "haiku",
 	"codex":  "gpt-4o-mini",
 	"cursor": "auto",
+	"openai": "gpt-4o-mini",
+	"ollama": "qwen2.5-coder",
 }
 
 const defaultPrompt = `You are a SQL expert. Based on the codebase context (schemas, migrations, models), generate ONLY the SQL query.
@@ -79,7 +81,7 @@ func runInit(cmd *cobra.Command, args []string) {
 	// Step 1: Detect repository
 	ui.Step("Detecting repository...")
 	ui.Pause()
-	repoName := detectRepoName(workDir)
+	repoName := prompt.RepoName(workDir)
 	ui.StepDone("%s", repoName)
 
 	// Handle --force: clear existing session
@@ -152,12 +154,26 @@ func runInit(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	// Prefer a model the backend actually has (e.g. whatever Ollama has pulled)
+	defaults := make(map[string]string, len(defaultModels))
+	for name, model := range defaultModels {
+		defaults[name] = model
+	}
+	if b, err := backend.Get(selected); err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), modelsTimeout)
+		models, _ := backend.ListModels(ctx, b)
+		cancel()
+		if len(models) > 0 && !backend.MatchModel(models, defaults[selected]) {
+			defaults[selected] = models[0]
+		}
+	}
+
 	config := Config{
 		Backend:   selected,
 		Dialect:   "postgresql",
 		DBVersion: "",
 		Timeout:   "2m",
-		Defaults:  defaultModels,
+		Defaults:  defaults,
 		Session: SessionConfig{
 			TTL: "7d",
 		},
@@ -176,7 +192,7 @@ func runInit(cmd *cobra.Command, args []string) {
 	}
 
 	ui.StepItem("Backend:  %s", selected)
-	ui.StepItem("Model:    %s", defaultModels[selected])
+	ui.StepItem("Model:    %s", defaults[selected])
 	ui.StepItem("Dialect:  %s", config.Dialect)
 	ui.StepItem("Session:  %s TTL", config.Session.TTL)
 
@@ -193,28 +209,6 @@ func runInit(cmd *cobra.Command, args []string) {
 	ui.Print("")
 }
 
-// detectRepoName returns the repository or directory name
-func detectRepoName(workDir string) string {
-	// Try to get git remote name
-	cmd := exec.Command("git", "remote", "get-url", "origin")
-	cmd.Dir = workDir
-	if out, err := cmd.Output(); err == nil {
-		url := strings.TrimSpace(string(out))
-		// Extract repo name from URL
-		// git@github.com:user/repo.git or https://github.com/user/repo.git
-		url = strings.TrimSuffix(url, ".git")
-		if idx := strings.LastIndex(url, "/"); idx != -1 {
-			return url[idx+1:]
-		}
-		if idx := strings.LastIndex(url, ":"); idx != -1 {
-			return url[idx+1:]
-		}
-	}
-
-	// Fall back to directory name
-	return filepath.Base(workDir)
-}
-
 // addToGitignore adds .qry/ to .gitignore if not already present
 func addToGitignore(workDir string) {
 	gitignorePath := workDir + "/.gitignore"
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/amansingh-afk/qry/internal/backend"
+	"github.com/amansingh-afk/qry/internal/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// modelsTimeout bounds model discovery; it runs before every -m query
+const modelsTimeout = 5 * time.Second
+
+var modelsCmd = &cobra.Command{
+	Use:               "models [backend]",
+	Short:             "List models a backend accepts",
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeBackendArg,
+	Run:               runModels,
+}
+
+func runModels(cmd *cobra.Command, args []string) {
+	var backends []backend.Backend
+	if len(args) == 1 {
+		b, err := backend.Get(args[0])
+		if err != nil {
+			ui.Error("%s", err.Error())
+			os.Exit(1)
+		}
+		backends = append(backends, b)
+	} else {
+		backends = backend.Available()
+	}
+
+	if len(backends) == 0 {
+		ui.Warning("No backends found. Run: qry init")
+		os.Exit(1)
+	}
+
+	for i, b := range backends {
+		if i > 0 {
+			fmt.Println()
+		}
+		printModels(b)
+	}
+}
+
+func printModels(b backend.Backend) {
+	ui.Step("%s", b.Name())
+
+	ctx, cancel := context.WithTimeout(context.Background(), modelsTimeout)
+	defer cancel()
+
+	models, err := backend.ListModels(ctx, b)
+	if err != nil {
+		ui.StepWarn("%s", firstLine(err.Error()))
+		return
+	}
+	if len(models) == 0 {
+		ui.StepItem("doesn't list its models; -m is passed through as-is")
+		return
+	}
+
+	def := backend.ModelFor(b.Name())
+	for _, m := range models {
+		switch {
+		case m == def:
+			ui.StepDone("%s (default)", m)
+		case strings.HasSuffix(m, "*"):
+			ui.StepItem("%s (pattern)", m)
+		default:
+			ui.StepItem("%s", m)
+		}
+	}
+}
+
+// checkModel rejects a model the backend says it doesn't have. Backends
+// that can't list models, or can't be reached, aren't checked.
+func checkModel(b backend.Backend, model string) error {
+	if model == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), modelsTimeout)
+	defer cancel()
+
+	models, err := backend.ListModels(ctx, b)
+	if err != nil || len(models) == 0 || backend.MatchModel(models, model) {
+		return nil
+	}
+
+	return fmt.Errorf("unknown model %q for %s\n\n  Available: %s\n  See: qry models %s",
+		model, b.Name(), strings.Join(models, ", "), b.Name())
+}
+
+// completeModels completes -m against the selected backend's models
+func completeModels(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	name := strings.Split(backendFlag, ",")[0]
+	if name == "" {
+		name = viper.GetString("backend")
+	}
+	b, err := backend.Get(name)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), modelsTimeout)
+	defer cancel()
+
+	models, _ := backend.ListModels(ctx, b)
+
+	var out []string
+	for _, m := range models {
+		if !strings.HasSuffix(m, "*") && strings.HasPrefix(m, toComplete) {
+			out = append(out, m)
+		}
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeBackends completes -b, including custom and plugin backends
+func completeBackends(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return backend.List(), cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeBackendArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeBackends(cmd, args, toComplete)
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 
 	"github.com/amansingh-afk/qry/internal/backend"
 	"github.com/amansingh-afk/qry/internal/guardrails"
 	"github.com/amansingh-afk/qry/internal/output"
 	"github.com/amansingh-afk/qry/internal/prompt"
 	"github.com/amansingh-afk/qry/internal/security"
+	"github.com/amansingh-afk/qry/internal/session"
 	"github.com/amansingh-afk/qry/internal/ui"
 	"github.com/spf13/cobra"
 )
@@ -22,20 +25,28 @@ var queryCmd = &cobra.Command{
 	Example: `  qry q "get active users"
   qry q "count orders" --json
   qry q "find users" -b claude -m sonnet
-  qry q "get recent" -d postgresql`,
+  qry q "get recent" -d postgresql
+  qry q "monthly revenue" --ensemble claude,codex,cursor`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		if ensembleFlag != "" {
+			runEnsemble(args[0], strings.Split(ensembleFlag, ","))
+			return
+		}
 		runQuery(args[0])
 	},
 }
 
+var ensembleFlag string
+
 func init() {
 	queryCmd.Flags().BoolVar(&jsonFlag, "json", false, "output JSON")
 	queryCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "show prompt without running")
+	queryCmd.Flags().StringVar(&ensembleFlag, "ensemble", "", "query several backends in parallel and compare (e.g. claude,codex)")
 }
 
 func runQuery(query string) {
-	b, err := getBackend()
+	chain, err := getBackendChain()
 	if err != nil {
 		ui.Error("%s", err.Error())
 		os.Exit(1)
@@ -43,52 +54,127 @@ func runQuery(query string) {
 
 	dialect := getDialect()
 
-	// Get existing session or empty string for new session
-	sessionID := getSession(b.Name())
-
-	// Use full prompt for new sessions, minimal prompt for existing sessions
-	var sqlPrompt string
-	if sessionID == "" {
-		sqlPrompt = prompt.BuildSQL(query, dialect)
-	} else {
-		sqlPrompt = prompt.BuildFollowUp(query)
+	// buildPrompt uses the full prompt for new sessions, minimal prompt for
+	// existing sessions, and the full prompt again if the rules changed since
+	buildPrompt := func(sessionID string) string {
+		switch {
+		case sessionID == "":
+			return prompt.BuildSQL(query, dialect)
+		case session.RulesChanged(workDir, sessionID, prompt.RulesHash()):
+			return prompt.BuildRefresh(query, dialect)
+		default:
+			return prompt.BuildFollowUp(query)
+		}
 	}
 
 	if dryRunFlag {
 		ui.Info("Prompt:")
-		fmt.Println(sqlPrompt)
+		fmt.Println(buildPrompt(""))
+		if id := getSession(chain[0].Name()); id != "" {
+			fmt.Println()
+			if session.RulesChanged(workDir, id, prompt.RulesHash()) {
+				ui.Hint("Session active, but the prompt or security rules changed since it started: this query would send them again")
+			} else {
+				ui.Hint("Session active: this query would send only the question. Reset with: qry init --force")
+			}
+		}
 		return
 	}
 
-	model := getModel(b.Name())
-
-	opts := backend.Options{
-		Model:     model,
-		Dialect:   dialect,
-		SessionID: sessionID,
-	}
-
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
 
-	ctx, cancelTimeout := context.WithTimeout(ctx, getTimeout())
-	defer cancelTimeout()
+	var (
+		model        string
+		fallbackFrom []string
+		filesRead    []string
+		sent         string
+		sentOpts     backend.Options
+	)
+
+	attempt := func(ctx context.Context, b backend.Backend) (backend.Result, error) {
+		// Only the primary backend resumes its session. Fallbacks run
+		// statelessly: looking one up would drop the primary's session.
+		var sessionID string
+		model = getModel(b.Name())
+		if b == chain[0] {
+			sessionID = getSession(b.Name())
+		} else {
+			model = getDefaultModel(b.Name())
+		}
 
-	ui.Thinking(b.Name())
+		opts := backend.Options{
+			Model:     model,
+			Dialect:   dialect,
+			SessionID: sessionID,
+		}
 
-	result, err := b.Query(ctx, sqlPrompt, workDir, opts)
+		if sessionID != "" && session.RulesChanged(workDir, sessionID, prompt.RulesHash()) {
+			ui.Info("Prompt or security rules changed since the session started, sending them again")
+		}
 
-	ui.ClearLine()
+		// Show what the agent is doing and note which files it read
+		set, stop := ui.Activity(b.Name())
+		defer stop()
 
+		filesRead = nil
+		sent, sentOpts = buildPrompt(sessionID), opts
+		events := backend.Stream(ctx, b, sent, workDir, opts)
+		return backend.Collect(events, func(ev backend.Event) {
+			if ev.Type != backend.EventActivity {
+				return
+			}
+			set(ev.Text)
+			if ev.Path != "" && !slices.Contains(filesRead, ev.Path) {
+				filesRead = append(filesRead, ev.Path)
+			}
+		})
+	}
+
+	onFail := func(f backend.Failure) {
+		if f.Retrying {
+			ui.Warning("%s: %s, retrying", f.Backend, f.Kind)
+			return
+		}
+		fallbackFrom = append(fallbackFrom, f.Backend)
+		if len(chain) > 1 {
+			ui.Warning("%s failed (%s)", f.Backend, f.Kind)
+		}
+	}
+
+	b, result, err := backend.Fallback(ctx, chain, getRetry(), attempt, onFail)
 	if err != nil {
 		ui.Error("%s", err.Error())
 		os.Exit(1)
 	}
 
-	// Save session for future queries
-	saveSession(b.Name(), result.SessionID)
+	out := prompt.ExtractReply(result.Response)
+
+	// A refusal or a question is a valid answer; only repair the rest
+	var answer *prompt.Answer
+	if prompt.Structured() && out.Kind != prompt.OutcomeRefusal && out.Kind != prompt.OutcomeClarification {
+		a, err := prompt.ResolveAnswer(result.Response, backend.Repair(ctx, b, sent, workDir, sentOpts, getTimeout(b.Name()), &result))
+		if err != nil {
+			ui.Warning("Invalid structured answer, using the raw reply: %s", err)
+		} else {
+			answer, out = &a, a.Outcome()
+		}
+	}
+
+	// Save session for future queries; a fallback's would replace the
+	// primary's, so only its usage is kept
+	if b != chain[0] {
+		result.SessionID = ""
+	}
+	saveSession(b.Name(), result)
 
-	sql := prompt.ExtractSQL(result.Response)
+	if out.Kind != prompt.OutcomeSQL {
+		exitOutcome(query, out, b.Name(), model, result)
+	}
+	sql := out.SQL
+	if n := len(out.Statements); n > 1 {
+		ui.Warning("The reply has %d statements", n)
+	}
 
 	// Security validation
 	secResult := security.Validate(sql)
@@ -110,8 +196,84 @@ func runQuery(query string) {
 	}
 
 	if jsonFlag {
-		output.JSON(os.Stdout, sql, b.Name(), model, dialect)
+		res := output.Result{
+			SQL:          sql,
+			Outcome:      out.Kind,
+			Backend:      b.Name(),
+			Model:        model,
+			Dialect:      dialect,
+			FallbackFrom: fallbackFrom,
+			Usage:        usagePtr(result.Usage),
+			FilesRead:    filesRead,
+
+			DefinitionsApplied: prompt.MatchGlossary(query),
+		}
+		if len(out.Statements) > 1 {
+			res.Statements = out.Statements
+		}
+		res.SetAnswer(answer)
+		output.JSON(os.Stdout, res)
 	} else {
-		output.Pretty(os.Stdout, sql, b.Name(), model)
+		output.Pretty(os.Stdout, sql, b.Name(), model, result.Usage, answer)
+	}
+}
+
+// exitOutcome reports a reply that isn't SQL and exits with its code
+func exitOutcome(query string, out prompt.Outcome, backendName, model string, result backend.Result) {
+	if jsonFlag {
+		output.JSON(os.Stdout, output.Result{
+			Outcome: out.Kind,
+			Message: out.Message,
+			Options: out.Options,
+			Backend: backendName,
+			Model:   model,
+			Dialect: getDialect(),
+			Usage:   usagePtr(result.Usage),
+		})
+	}
+
+	switch out.Kind {
+	case prompt.OutcomeRefusal:
+		if !jsonFlag {
+			ui.Error("%s declined to write this query", backendName)
+			fmt.Fprintln(os.Stderr, out.Message)
+		}
+		os.Exit(exitRefused)
+	case prompt.OutcomeClarification:
+		if !jsonFlag {
+			ui.Warning("%s needs more detail:", backendName)
+			fmt.Println(out.Message)
+			for i, o := range out.Options {
+				fmt.Printf("  %d. %s\n", i+1, o)
+			}
+
+			example := "<your answer>"
+			if len(out.Options) > 0 {
+				example = out.Options[0]
+			}
+			fmt.Println()
+			if result.SessionID != "" {
+				ui.Hint("Answer in the same session: qry q %q", example)
+			} else {
+				ui.Hint("Ask again with the detail, e.g. qry q %q", query+" ("+example+")")
+			}
+		}
+		os.Exit(exitClarification)
+	default:
+		if !jsonFlag {
+			ui.Error("No SQL in the reply from %s", backendName)
+			if out.Message != "" {
+				fmt.Fprintln(os.Stderr, out.Message)
+			}
+		}
+		os.Exit(exitNoSQL)
+	}
+}
+
+// usagePtr returns nil for zero usage so it's omitted from JSON
+func usagePtr(u backend.Usage) *backend.Usage {
+	if u.IsZero() {
+		return nil
 	}
+	return &u
 }
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestQryProcess is qry itself when run by runQry. Commands exit the
+// process, so each run gets its own.
+func TestQryProcess(t *testing.T) {
+	args := os.Getenv("QRY_TEST_ARGS")
+	if args == "" {
+		return
+	}
+	rootCmd.SetArgs(strings.Split(args, "\n"))
+	Execute()
+	os.Exit(0)
+}
+
+// runQry runs qry with args in dir and returns stdout, stderr and the
+// exit code
+func runQry(t *testing.T, dir string, args ...string) (string, string, int) {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestQryProcess$")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "QRY_TEST_ARGS="+strings.Join(args, "\n"))
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatal(err)
+	}
+	return stdout.String(), stderr.String(), cmd.ProcessState.ExitCode()
+}
+
+// TestQueryReplay drives `qry q` through testdata/cassette.json, so the
+// prompt, SQL extraction, security and guardrails all run without a model.
+// The cassette was recorded from claude with the config below; a change to
+// the default prompt shows up here as "no recording".
+func TestQueryReplay(t *testing.T) {
+	cassette, err := filepath.Abs(filepath.Join("testdata", "cassette.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	config := `backend: replay
+dialect: postgresql
+backends:
+  replay:
+    cassette: ` + cassette + `
+security:
+  mode: strict
+  exclude:
+    tables: [api_keys]
+`
+	if err := os.WriteFile(filepath.Join(dir, ".qry.yaml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		question string
+		code     int
+		outcome  string // JSON outcome, when the command prints one
+		sql      string
+		stderr   string
+	}{
+		{question: "active users", outcome: "sql", sql: "SELECT id, email\nFROM users\nWHERE active = true;"},
+		{question: "users with their api keys", code: 1, stderr: "table: api_keys JOIN clause"},
+		{question: "remove expired sessions", outcome: "sql", sql: "DELETE FROM sessions WHERE expires_at < now()", stderr: "Destructive operation: DELETE FROM"},
+		{question: "revenue last quarter", code: exitClarification, outcome: "clarification"},
+		{question: "never recorded", code: 1, stderr: "no recording for this prompt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.question, func(t *testing.T) {
+			stdout, stderr, code := runQry(t, dir, "q", tt.question, "--json")
+			if code != tt.code {
+				t.Fatalf("exit code = %d, want %d\nstdout: %s\nstderr: %s", code, tt.code, stdout, stderr)
+			}
+			if !strings.Contains(stderr, tt.stderr) {
+				t.Errorf("stderr doesn't mention %q:\n%s", tt.stderr, stderr)
+			}
+			if tt.outcome == "" {
+				return
+			}
+
+			var res struct {
+				SQL     string `json:"sql"`
+				Outcome string `json:"outcome"`
+				Backend string `json:"backend"`
+			}
+			if err := json.Unmarshal([]byte(stdout), &res); err != nil {
+				t.Fatalf("invalid JSON: %v\n%s", err, stdout)
+			}
+			if res.Outcome != tt.outcome || res.SQL != tt.sql || res.Backend != "replay" {
+				t.Errorf("got %+v, want outcome %q, SQL %q from replay", res, tt.outcome, tt.sql)
+			}
+		})
+	}
+}
@@ -3,9 +3,11 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/amansingh-afk/qry/internal/backend"
+	"github.com/amansingh-afk/qry/internal/prompt"
 	"github.com/amansingh-afk/qry/internal/session"
 	"github.com/amansingh-afk/qry/internal/ui"
 	"github.com/spf13/cobra"
@@ -17,6 +19,7 @@ var (
 	modelFlag   string
 	dialectFlag string
 	timeoutFlag time.Duration
+	recordFlag  bool
 	jsonFlag    bool
 	dryRunFlag  bool
 	workDir     string
@@ -29,6 +32,13 @@ var rootCmd = &cobra.Command{
 	Run:   runChat, // Default: interactive chat
 }
 
+// Exit codes for `qry q` when the reply isn't SQL; 1 is any other failure
+const (
+	exitRefused       = 3
+	exitClarification = 4
+	exitNoSQL         = 5
+)
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -38,12 +48,20 @@ func Execute() {
 func init() {
 	cobra.OnInitialize(loadConfig)
 
-	rootCmd.PersistentFlags().StringVarP(&backendFlag, "backend", "b", "", "backend (claude, codex, cursor)")
+	rootCmd.PersistentFlags().StringVarP(&backendFlag, "backend", "b", "", "backend (claude, codex, cursor, openai, ollama, replay, a plugin, or one from .qry.yaml)")
 	rootCmd.PersistentFlags().StringVarP(&modelFlag, "model", "m", "", "model to use")
 	rootCmd.PersistentFlags().StringVarP(&dialectFlag, "dialect", "d", "", "SQL dialect (postgresql, mysql, sqlite)")
 	rootCmd.PersistentFlags().DurationVarP(&timeoutFlag, "timeout", "t", 0, "timeout")
+	rootCmd.PersistentFlags().BoolVar(&recordFlag, "record", false, "save responses to a cassette for the replay backend")
+
+	_ = rootCmd.RegisterFlagCompletionFunc("backend", completeBackends)
+	_ = rootCmd.RegisterFlagCompletionFunc("model", completeModels)
 
 	rootCmd.AddCommand(queryCmd)
+	rootCmd.AddCommand(explainCmd)
+	rootCmd.AddCommand(translateCmd)
+	rootCmd.AddCommand(modelsCmd)
+	rootCmd.AddCommand(doctorCmd)
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(serveCmd)
 	rootCmd.AddCommand(versionCmd)
@@ -61,11 +79,36 @@ func loadConfig() {
 	viper.SetDefault("dialect", "postgresql")
 	viper.SetDefault("timeout", "2m")
 	viper.SetDefault("session.ttl", "7d")
+	viper.SetDefault("retry.attempts", 2)
+	viper.SetDefault("retry.backoff", "2s")
 	viper.SetDefault("defaults.claude", "haiku")
 	viper.SetDefault("defaults.codex", "gpt-4o-mini")
 	viper.SetDefault("defaults.cursor", "auto")
+	viper.SetDefault("defaults.openai", "gpt-4o-mini")
+	viper.SetDefault("defaults.ollama", "qwen2.5-coder")
 
 	_ = viper.ReadInConfig()
+
+	// Register exec backends declared in .qry.yaml
+	if err := backend.LoadConfigured(); err != nil {
+		ui.Warning("Invalid backend config:\n%s", err)
+	}
+
+	// Parse the prompt template and .qry/prompts/ once, up front
+	if err := prompt.Load(workDir); err != nil {
+		ui.Warning("Invalid prompt template, using the default:\n%s", err)
+	}
+	if _, err := prompt.LoadExamples(workDir); err != nil {
+		ui.Warning("Invalid examples, ignoring them:\n%s", err)
+	}
+
+	// Register qry-backend-<name> plugins found on PATH
+	backend.LoadPlugins()
+
+	backend.SetRecording(recordFlag)
+
+	// Note the config's state, so serve and the TUI can spot edits
+	prompt.Refresh(workDir)
 }
 
 func getBackend() (backend.Backend, error) {
@@ -83,27 +126,94 @@ func getBackend() (backend.Backend, error) {
 		return nil, fmt.Errorf("%s not installed\n\n  Install: %s", b.Name(), b.InstallCmd())
 	}
 
+	if err := checkModel(b, modelFlag); err != nil {
+		return nil, err
+	}
+
 	return b, nil
 }
 
+// getBackendChain returns the backends to try in order.
+// `-b a,b` sets the chain explicitly; `-b a` disables fallback;
+// otherwise it's the configured backend followed by `fallback:`.
+func getBackendChain() ([]backend.Backend, error) {
+	var names []string
+	if backendFlag != "" {
+		names = strings.Split(backendFlag, ",")
+	} else {
+		names = append([]string{viper.GetString("backend")}, viper.GetStringSlice("fallback")...)
+	}
+
+	var chain []backend.Backend
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		b, err := backend.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, b)
+	}
+
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no backend configured")
+	}
+
+	// -m applies to the first backend only
+	if err := checkModel(chain[0], modelFlag); err != nil {
+		return nil, err
+	}
+
+	// Fail fast with an install hint if nothing in the chain can run
+	for _, b := range chain {
+		if b.Available() {
+			return chain, nil
+		}
+	}
+	return nil, fmt.Errorf("%s not installed\n\n  Install: %s", chain[0].Name(), chain[0].InstallCmd())
+}
+
+// getRetry returns the retry policy for fallback chains
+func getRetry() backend.Retry {
+	return backend.Retry{
+		Attempts: viper.GetInt("retry.attempts"),
+		Backoff:  viper.GetDuration("retry.backoff"),
+		Timeout: func(b backend.Backend) time.Duration {
+			return getTimeout(b.Name())
+		},
+	}
+}
+
 func getModel(backendName string) string {
 	// 1. CLI flag takes priority
 	if modelFlag != "" {
 		return modelFlag
 	}
 
-	// 2. Config file "model" field
-	if model := viper.GetString("model"); model != "" {
+	// 2. Backend block "backends.<name>.model"
+	if model := backend.ConfigFor(backendName).Model; model != "" {
 		return model
 	}
 
-	// 3. Backend-specific default from config
-	if model := viper.GetString("defaults." + backendName); model != "" {
+	// 3. Config file "model" field
+	if model := viper.GetString("model"); model != "" {
 		return model
 	}
 
-	// 4. Hardcoded fallback (shouldn't reach here if config exists)
-	return ""
+	// 4. Backend-specific default from config
+	return viper.GetString("defaults." + backendName)
+}
+
+// getDefaultModel returns the backend's own default model. Used for
+// backends reached via fallback or ensemble, since -m and the top-level
+// `model` target the primary backend.
+func getDefaultModel(backendName string) string {
+	return backend.ModelFor(backendName)
 }
 
 func getDialect() string {
@@ -113,14 +223,12 @@ func getDialect() string {
 	return viper.GetString("dialect")
 }
 
-func getTimeout() time.Duration {
+// getTimeout returns -t, then backends.<name>.timeout, then `timeout`
+func getTimeout(backendName string) time.Duration {
 	if timeoutFlag > 0 {
 		return timeoutFlag
 	}
-	if t := viper.GetDuration("timeout"); t > 0 {
-		return t
-	}
-	return 2 * time.Minute
+	return backend.TimeoutFor(backendName)
 }
 
 var versionCmd = &cobra.Command{
@@ -134,25 +242,29 @@ var versionCmd = &cobra.Command{
 // getSessionTTL parses the session TTL from config
 // Supports formats like "7d", "24h", "168h"
 func getSessionTTL() time.Duration {
-	ttlStr := viper.GetString("session.ttl")
+	ttl, err := parseTTL(viper.GetString("session.ttl"))
+	if err != nil {
+		return 7 * 24 * time.Hour // Fallback
+	}
+	return ttl
+}
+
+// parseTTL parses "Xd" (days) or a Go duration. Empty means the default.
+func parseTTL(ttlStr string) (time.Duration, error) {
 	if ttlStr == "" {
-		return 7 * 24 * time.Hour // Default 7 days
+		return 7 * 24 * time.Hour, nil // Default 7 days
 	}
 
 	// Handle "Xd" format (days)
 	if len(ttlStr) > 1 && ttlStr[len(ttlStr)-1] == 'd' {
 		var days int
 		if _, err := fmt.Sscanf(ttlStr, "%dd", &days); err == nil {
-			return time.Duration(days) * 24 * time.Hour
+			return time.Duration(days) * 24 * time.Hour, nil
 		}
 	}
 
 	// Try standard duration format
-	if d, err := time.ParseDuration(ttlStr); err == nil {
-		return d
-	}
-
-	return 7 * 24 * time.Hour // Fallback
+	return time.ParseDuration(ttlStr)
 }
 
 // getSession returns the current session ID if valid, empty string otherwise
@@ -164,10 +276,14 @@ func getSession(backendName string) string {
 	return s.SessionID
 }
 
-// saveSession persists the session ID for future use
-func saveSession(backendName, sessionID string) {
-	if sessionID == "" {
-		return
+// saveSession persists the session ID, if any, and adds the query's usage
+// to the totals. Stateless backends have no session but still count.
+func saveSession(backendName string, result backend.Result) {
+	if result.SessionID != "" {
+		_ = session.Update(workDir, backendName, result.SessionID, prompt.RulesHash())
+	}
+
+	if u := result.Usage; !u.IsZero() {
+		_ = session.AddUsage(workDir, backendName, u.InputTokens, u.OutputTokens, u.CostUSD)
 	}
-	_ = session.Update(workDir, backendName, sessionID)
 }
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/amansingh-afk/qry/internal/backend"
+	"github.com/amansingh-afk/qry/internal/dialect"
+	"github.com/amansingh-afk/qry/internal/guardrails"
+	"github.com/amansingh-afk/qry/internal/output"
+	"github.com/amansingh-afk/qry/internal/prompt"
+	"github.com/amansingh-afk/qry/internal/security"
+	"github.com/amansingh-afk/qry/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fromFlag string
+	toFlag   string
+)
+
+var translateCmd = &cobra.Command{
+	Use:   "translate [sql]",
+	Short: "Translate SQL to another dialect",
+	Example: `  qry translate --from mysql --to postgresql "SELECT IFNULL(name, '') FROM users LIMIT 10, 20"
+  qry translate --to postgresql < reports/monthly.sql
+  qry translate --from mysql --to postgresql --json < report.sql`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runTranslate(args)
+	},
+}
+
+func init() {
+	translateCmd.Flags().StringVar(&fromFlag, "from", "", "dialect the SQL is written in (default: dialect)")
+	translateCmd.Flags().StringVar(&toFlag, "to", "", "dialect to translate to")
+	translateCmd.Flags().BoolVar(&jsonFlag, "json", false, "output JSON")
+	translateCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "show prompt without running")
+	_ = translateCmd.MarkFlagRequired("to")
+}
+
+func runTranslate(args []string) {
+	from := fromFlag
+	if from == "" {
+		from = getDialect()
+	}
+	from, err := dialect.Normalize(from)
+	if err != nil {
+		ui.Error("--from: %s", err.Error())
+		os.Exit(1)
+	}
+	to, err := dialect.Normalize(toFlag)
+	if err != nil {
+		ui.Error("--to: %s", err.Error())
+		os.Exit(1)
+	}
+	if from == to {
+		ui.Error("--from and --to are both %s", to)
+		os.Exit(1)
+	}
+
+	sql, err := readSQLArg(args)
+	if err != nil {
+		ui.Error("%s", err.Error())
+		os.Exit(1)
+	}
+
+	chain, err := getBackendChain()
+	if err != nil {
+		ui.Error("%s", err.Error())
+		os.Exit(1)
+	}
+
+	translatePrompt := prompt.BuildTranslate(sql, from, to)
+
+	if dryRunFlag {
+		ui.Info("Prompt:")
+		fmt.Println(translatePrompt)
+		return
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	var model string
+
+	attempt := func(ctx context.Context, b backend.Backend) (backend.Result, error) {
+		model = getModel(b.Name())
+		if b != chain[0] {
+			model = getDefaultModel(b.Name())
+		}
+
+		set, stop := ui.Activity(b.Name())
+		defer stop()
+
+		return explainSQL(ctx, b, translatePrompt, backend.Options{Model: model, Dialect: to}, set)
+	}
+
+	onFail := func(f backend.Failure) {
+		if f.Retrying {
+			ui.Warning("%s: %s, retrying", f.Backend, f.Kind)
+			return
+		}
+		if len(chain) > 1 {
+			ui.Warning("%s failed (%s)", f.Backend, f.Kind)
+		}
+	}
+
+	b, result, err := backend.Fallback(ctx, chain, getRetry(), attempt, onFail)
+	if err != nil {
+		ui.Error("%s", err.Error())
+		os.Exit(1)
+	}
+
+	// There's no session, so the repair repeats the original prompt
+	ask := backend.Repair(ctx, b, translatePrompt, workDir, backend.Options{Model: model, Dialect: to}, getTimeout(b.Name()), &result)
+	translated, issues, err := prompt.ResolveTranslation(result.Response, to, ask)
+	if err != nil {
+		ui.Error("%s", err.Error())
+		os.Exit(exitNoSQL)
+	}
+
+	if len(issues) > 0 {
+		ui.Warning("The translation still uses constructs %s doesn't support:", to)
+		for _, i := range issues {
+			fmt.Fprintln(os.Stderr, "  - "+i.String())
+		}
+	}
+
+	// The translation is new SQL, so it's checked like any other
+	secResult := security.Validate(translated)
+	sec := security.Get()
+
+	if sec.IsBlocked(secResult) {
+		ui.Error("Security violation: query blocked")
+		fmt.Fprintln(os.Stderr, secResult.Error())
+		os.Exit(1)
+	}
+
+	var securityWarning string
+	if sec.ShouldWarn(secResult) {
+		securityWarning = secResult.Error()
+		ui.Warning("Security warning: query references restricted data")
+		fmt.Fprintln(os.Stderr, securityWarning)
+	}
+
+	warning := guardrails.Check(translated)
+	if warning != "" {
+		ui.Warning("%s", warning)
+	}
+
+	if jsonFlag {
+		output.TranslateJSON(os.Stdout, output.Translation{
+			SQL:     translated,
+			From:    from,
+			To:      to,
+			Backend: b.Name(),
+			Model:   model,
+			Issues:  issues,
+			Usage:   usagePtr(result.Usage),
+
+			Warning:         warning,
+			SecurityWarning: securityWarning,
+		})
+	} else {
+		output.Pretty(os.Stdout, translated, b.Name(), model, result.Usage, nil)
+	}
+}
@@ -3,6 +3,8 @@ package backend
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 )
 
 type Options struct {
@@ -14,6 +16,50 @@ type Options struct {
 type Result struct {
 	Response  string
 	SessionID string // Returned for multi-turn conversations
+	Usage     Usage  // Zero if the backend doesn't report usage
+}
+
+// Usage reports tokens and cost for a query, as returned by the backend
+type Usage struct {
+	InputTokens  int     `json:"input_tokens"` // Includes cache reads/writes
+	OutputTokens int     `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd,omitempty"`
+	Turns        int     `json:"turns,omitempty"`
+}
+
+// IsZero reports whether the backend returned no usage data
+func (u Usage) IsZero() bool {
+	return u == Usage{}
+}
+
+// Add sums two usages, e.g. a query and its repair retry
+func (u Usage) Add(o Usage) Usage {
+	return Usage{
+		InputTokens:  u.InputTokens + o.InputTokens,
+		OutputTokens: u.OutputTokens + o.OutputTokens,
+		CostUSD:      u.CostUSD + o.CostUSD,
+		Turns:        u.Turns + o.Turns,
+	}
+}
+
+// Summary formats usage for display, e.g. "12.4k tokens · $0.0213"
+func (u Usage) Summary() string {
+	if u.IsZero() {
+		return ""
+	}
+
+	tokens := u.InputTokens + u.OutputTokens
+	var s string
+	if tokens >= 1000 {
+		s = fmt.Sprintf("%.1fk tokens", float64(tokens)/1000)
+	} else {
+		s = fmt.Sprintf("%d tokens", tokens)
+	}
+
+	if u.CostUSD > 0 {
+		s += fmt.Sprintf(" · $%.4f", u.CostUSD)
+	}
+	return s
 }
 
 type Backend interface {
@@ -23,11 +69,52 @@ type Backend interface {
 	Query(ctx context.Context, prompt string, workDir string, opts Options) (Result, error)
 }
 
+// ModelLister is implemented by backends that can list their models.
+// Entries ending in "*" are patterns, e.g. "claude-*" for full model IDs.
+type ModelLister interface {
+	Models(ctx context.Context) ([]string, error)
+}
+
+// ListModels returns b's models, or nil if it can't list them
+func ListModels(ctx context.Context, b Backend) ([]string, error) {
+	if l, ok := b.(ModelLister); ok {
+		return l.Models(ctx)
+	}
+	return nil, nil
+}
+
+// MatchModel reports whether model is in the list or matches a pattern
+func MatchModel(models []string, model string) bool {
+	for _, m := range models {
+		if m == model {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(m, "*"); ok && strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 var registry = map[string]Backend{
 	"claude": &Claude{},
 	// "gemini": &Gemini{}, // WIP: needs account testing
 	"codex":  &Codex{},
 	"cursor": &Cursor{},
+	"openai": &OpenAI{},
+	"ollama": &Ollama{},
+	"replay": &Replay{},
+}
+
+// builtin lists the built-in backends in detection order
+var builtin = []string{"claude", "codex", "cursor", "openai", "ollama", "replay"}
+
+// recording wraps every backend returned by Get in a Recorder
+var recording bool
+
+// SetRecording turns cassette recording on or off for all backends
+func SetRecording(on bool) {
+	recording = on
 }
 
 func Get(name string) (Backend, error) {
@@ -35,11 +122,39 @@ func Get(name string) (Backend, error) {
 	if !ok {
 		return nil, fmt.Errorf("unknown backend: %s", name)
 	}
+	if recording {
+		return Record(b), nil
+	}
 	return b, nil
 }
 
+// Register adds a backend that isn't compiled in (e.g. from config)
+func Register(name string, b Backend) {
+	registry[name] = b
+}
+
+func isBuiltin(name string) bool {
+	for _, n := range builtin {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// List returns built-in backends first, then registered ones by name
 func List() []string {
-	return []string{"claude", "codex", "cursor"}
+	names := append([]string{}, builtin...)
+
+	var extra []string
+	for name := range registry {
+		if !isBuiltin(name) {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+
+	return append(names, extra...)
 }
 
 func Available() []Backend {
@@ -1,9 +1,12 @@
 package backend
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os/exec"
 	"strings"
 )
@@ -17,18 +20,91 @@ func (c *Claude) InstallCmd() string {
 }
 
 func (c *Claude) Available() bool {
-	_, err := exec.LookPath("claude")
+	_, err := exec.LookPath(ConfigFor("claude").binary("claude"))
 	return err == nil
 }
 
+// Models returns the aliases the CLI accepts. Full model IDs work too.
+func (c *Claude) Models(ctx context.Context) ([]string, error) {
+	return []string{"haiku", "sonnet", "opus", "claude-*"}, nil
+}
+
+// Health checks the CLI runs. Auth shows up in the round trip.
+func (c *Claude) Health(ctx context.Context) (Health, error) {
+	return cliVersion(ctx, "claude", ConfigFor("claude").binary("claude"))
+}
+
+// Credentials for the Anthropic API, Bedrock and Vertex
+var claudeEnvPrefixes = []string{"ANTHROPIC_", "AWS_", "CLOUD_ML_", "VERTEX_", "GOOGLE_"}
+
+// claudeSandboxArgs allows only read tools, denies the rest explicitly (a
+// global allow rule would otherwise win) and ignores configured MCP servers
+func claudeSandboxArgs(sb Sandbox, workDir string) []string {
+	if !sb.Enabled() {
+		return nil
+	}
+	args := []string{
+		"--allowedTools", strings.Join(sb.AllowedTools, ","),
+		"--disallowedTools", strings.Join(sb.DeniedTools(), ","),
+		"--strict-mcp-config",
+	}
+	for _, dir := range sb.ExtraDirs(workDir) {
+		args = append(args, "--add-dir", dir)
+	}
+	return args
+}
+
 // claudeJSONResponse represents the JSON output from claude CLI
 type claudeJSONResponse struct {
 	SessionID string `json:"session_id"`
 	Result    string `json:"result"`
+	claudeUsageFields
+}
+
+// claudeUsageFields are the usage fields shared by json and stream-json results
+type claudeUsageFields struct {
+	TotalCostUSD float64 `json:"total_cost_usd"`
+	CostUSD      float64 `json:"cost_usd"` // Older CLI versions
+	NumTurns     int     `json:"num_turns"`
+	Usage        struct {
+		InputTokens              int `json:"input_tokens"`
+		OutputTokens             int `json:"output_tokens"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	} `json:"usage"`
+}
+
+func (f claudeUsageFields) usage() Usage {
+	cost := f.TotalCostUSD
+	if cost == 0 {
+		cost = f.CostUSD
+	}
+	return Usage{
+		InputTokens:  f.Usage.InputTokens + f.Usage.CacheCreationInputTokens + f.Usage.CacheReadInputTokens,
+		OutputTokens: f.Usage.OutputTokens,
+		CostUSD:      cost,
+		Turns:        f.NumTurns,
+	}
 }
 
 func (c *Claude) Query(ctx context.Context, prompt string, workDir string, opts Options) (Result, error) {
-	args := []string{"-p", prompt, "--output-format", "json"}
+	cfg := ConfigFor("claude")
+	sb := SandboxFor("claude")
+	if err := sb.Check(); err != nil {
+		return Result{}, fmt.Errorf("claude: %w", err)
+	}
+	repo := workDir
+	workDir, err := cfg.dir(repo)
+	if err != nil {
+		return Result{}, fmt.Errorf("claude: %w", err)
+	}
+
+	// With -p and no prompt argument, claude reads the prompt from stdin
+	args := []string{"-p", "--output-format", "json"}
+	args = append(args, claudeSandboxArgs(sb, workDir)...)
+	for _, dir := range schemaDirs(repo, sb.Dir(workDir)) {
+		args = append(args, "--add-dir", dir)
+	}
 
 	if opts.SessionID != "" {
 		args = append(args, "--resume", opts.SessionID)
@@ -38,12 +114,15 @@ func (c *Claude) Query(ctx context.Context, prompt string, workDir string, opts
 		args = append(args, "--model", opts.Model)
 	}
 
-	cmd := exec.CommandContext(ctx, "claude", args...)
-	cmd.Dir = workDir
+	cmd := exec.CommandContext(ctx, cfg.binary("claude"), args...)
+	cmd.Dir = sb.Dir(workDir)
+	cmd.Env = sb.Environ("claude", claudeEnvPrefixes...)
+	cmd.Stdin = strings.NewReader(prompt)
+	cfg.apply(cmd)
 
 	out, err := cmd.CombinedOutput()
 	if err != nil {
-		return Result{}, fmt.Errorf("claude: %w\n%s", err, string(out))
+		return Result{}, cliError(ctx, "claude", err, out)
 	}
 
 	// Parse JSON response
@@ -56,5 +135,195 @@ func (c *Claude) Query(ctx context.Context, prompt string, workDir string, opts
 	return Result{
 		Response:  resp.Result,
 		SessionID: resp.SessionID,
+		Usage:     resp.usage(),
 	}, nil
 }
+
+// claudeStreamEvent represents one line of `--output-format stream-json`
+type claudeStreamEvent struct {
+	Type      string `json:"type"`
+	Subtype   string `json:"subtype"`
+	SessionID string `json:"session_id"`
+	Result    string `json:"result"`
+	IsError   bool   `json:"is_error"`
+	claudeUsageFields
+	Message *struct {
+		Content []struct {
+			Type  string         `json:"type"`
+			Text  string         `json:"text"`
+			Name  string         `json:"name"`
+			Input map[string]any `json:"input"`
+		} `json:"content"`
+	} `json:"message"`
+	// Present with --include-partial-messages
+	Event *struct {
+		Type  string `json:"type"`
+		Delta *struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"delta"`
+	} `json:"event"`
+}
+
+// QueryStream runs claude with stream-json output and reports partial
+// text and tool use as it happens
+func (c *Claude) QueryStream(ctx context.Context, prompt string, workDir string, opts Options) (<-chan Event, error) {
+	cfg := ConfigFor("claude")
+	sb := SandboxFor("claude")
+	if err := sb.Check(); err != nil {
+		return nil, fmt.Errorf("claude: %w", err)
+	}
+	repo := workDir
+	workDir, err := cfg.dir(repo)
+	if err != nil {
+		return nil, fmt.Errorf("claude: %w", err)
+	}
+
+	args := []string{"-p", "--output-format", "stream-json", "--verbose", "--include-partial-messages"}
+	args = append(args, claudeSandboxArgs(sb, workDir)...)
+	for _, dir := range schemaDirs(repo, sb.Dir(workDir)) {
+		args = append(args, "--add-dir", dir)
+	}
+
+	if opts.SessionID != "" {
+		args = append(args, "--resume", opts.SessionID)
+	}
+
+	if opts.Model != "" {
+		args = append(args, "--model", opts.Model)
+	}
+
+	cmd := exec.CommandContext(ctx, cfg.binary("claude"), args...)
+	cmd.Dir = sb.Dir(workDir)
+	cmd.Env = sb.Environ("claude", claudeEnvPrefixes...)
+	cmd.Stdin = strings.NewReader(prompt)
+	cfg.apply(cmd)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("claude: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, transportError(ctx, "claude", err)
+	}
+
+	events := make(chan Event, 16)
+
+	go func() {
+		defer close(events)
+
+		stream, scanErr := readClaudeStream(stdout, workDir, events)
+
+		// Let the CLI finish writing if the scanner stopped early, so it
+		// doesn't block on a full pipe and Wait can return
+		_, _ = io.Copy(io.Discard, stdout)
+		waitErr := cmd.Wait()
+
+		final := stream.final
+		if final != nil && !final.IsError {
+			if final.SessionID == "" {
+				final.SessionID = stream.sessionID
+			}
+			events <- Event{Type: EventDone, Result: Result{
+				Response:  final.Result,
+				SessionID: final.SessionID,
+				Usage:     final.usage(),
+			}}
+			return
+		}
+
+		if waitErr != nil {
+			events <- Event{Type: EventError, Err: cliError(ctx, "claude", waitErr, stderr.Bytes())}
+			return
+		}
+
+		if scanErr != nil {
+			events <- Event{Type: EventError, Err: malformedError("claude", fmt.Errorf("claude: reading output: %w", scanErr))}
+			return
+		}
+
+		if final != nil {
+			events <- Event{Type: EventError, Err: cliError(ctx, "claude", fmt.Errorf("error result"), []byte(final.Result))}
+			return
+		}
+
+		events <- Event{Type: EventDone, Result: Result{
+			Response:  strings.TrimSpace(stream.raw),
+			SessionID: stream.sessionID,
+		}}
+	}()
+
+	return events, nil
+}
+
+// claudeStream is what a stream-json run leaves once its output ends
+type claudeStream struct {
+	final     *claudeStreamEvent // The "result" line, if any
+	sessionID string             // Last session ID seen on any line
+	raw       string             // Lines that weren't JSON (older CLIs)
+}
+
+// readClaudeStream sends partial text and tool use from stream-json
+// output to events as they arrive, and returns the rest once r ends or
+// can't be read
+func readClaudeStream(r io.Reader, workDir string, events chan<- Event) (claudeStream, error) {
+	var (
+		stream    claudeStream
+		sawDeltas bool
+		raw       strings.Builder
+	)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+
+		var ev claudeStreamEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			// Not stream-json (older CLI): keep as plain text
+			raw.Write(line)
+			raw.WriteString("\n")
+			continue
+		}
+
+		if ev.SessionID != "" {
+			stream.sessionID = ev.SessionID
+		}
+
+		switch ev.Type {
+		case "stream_event":
+			if ev.Event != nil && ev.Event.Delta != nil && ev.Event.Delta.Type == "text_delta" {
+				sawDeltas = true
+				events <- Event{Type: EventText, Text: ev.Event.Delta.Text}
+			}
+
+		case "assistant":
+			if ev.Message == nil {
+				continue
+			}
+			for _, block := range ev.Message.Content {
+				switch block.Type {
+				case "text":
+					if !sawDeltas && block.Text != "" {
+						events <- Event{Type: EventText, Text: block.Text}
+					}
+				case "tool_use":
+					desc, path := describeTool(block.Name, block.Input, workDir)
+					events <- Event{Type: EventActivity, Text: desc, Path: path}
+				}
+			}
+
+		case "result":
+			e := ev
+			stream.final = &e
+		}
+	}
+
+	stream.raw = raw.String()
+	return stream, scanner.Err()
+}
@@ -0,0 +1,145 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// claudeStreamOutput is a trimmed `claude -p --output-format stream-json
+// --verbose --include-partial-messages` run
+const claudeStreamOutput = `{"type":"system","subtype":"init","session_id":"s1"}
+{"type":"stream_event","session_id":"s1","event":{"type":"content_block_delta","delta":{"type":"text_delta","text":"SELECT "}}}
+{"type":"assistant","session_id":"s1","message":{"content":[{"type":"tool_use","name":"Read","input":{"file_path":"/repo/db/schema.sql"}}]}}
+{"type":"stream_event","session_id":"s1","event":{"type":"content_block_delta","delta":{"type":"text_delta","text":"1"}}}
+{"type":"assistant","session_id":"s1","message":{"content":[{"type":"text","text":"SELECT 1"}]}}
+{"type":"result","subtype":"success","result":"SELECT 1","usage":{"input_tokens":10,"cache_read_input_tokens":5,"output_tokens":2},"total_cost_usd":0.01}
+`
+
+// readStream runs readClaudeStream over out and returns what it sent
+func readStream(t *testing.T, out io.Reader) (claudeStream, []Event, error) {
+	t.Helper()
+	events := make(chan Event, 64)
+	stream, err := readClaudeStream(out, "/repo", events)
+	close(events)
+
+	var got []Event
+	for ev := range events {
+		got = append(got, ev)
+	}
+	return stream, got, err
+}
+
+func TestReadClaudeStream(t *testing.T) {
+	stream, events, err := readStream(t, strings.NewReader(claudeStreamOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The full text block repeats the deltas, so it's skipped
+	want := []Event{
+		{Type: EventText, Text: "SELECT "},
+		{Type: EventActivity, Text: "reading db/schema.sql", Path: "db/schema.sql"},
+		{Type: EventText, Text: "1"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("events = %+v, want %+v", events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, events[i], want[i])
+		}
+	}
+
+	if stream.final == nil || stream.final.Result != "SELECT 1" {
+		t.Fatalf("final = %+v, want the result line", stream.final)
+	}
+	if u := stream.final.usage(); u.InputTokens != 15 || u.OutputTokens != 2 || u.CostUSD != 0.01 {
+		t.Errorf("usage = %+v", u)
+	}
+	if stream.sessionID != "s1" {
+		t.Errorf("sessionID = %q, want s1", stream.sessionID)
+	}
+}
+
+func TestReadClaudeStreamWithoutDeltas(t *testing.T) {
+	out := `{"type":"assistant","message":{"content":[{"type":"text","text":"SELECT 1"}]}}` + "\n"
+	_, events, err := readStream(t, strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0] != (Event{Type: EventText, Text: "SELECT 1"}) {
+		t.Errorf("events = %+v, want the text block", events)
+	}
+}
+
+func TestReadClaudeStreamPlainText(t *testing.T) {
+	stream, events, err := readStream(t, strings.NewReader("SELECT 1\nFROM t\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 || stream.final != nil {
+		t.Errorf("events = %+v, final = %+v; want neither", events, stream.final)
+	}
+	if stream.raw != "SELECT 1\nFROM t\n" {
+		t.Errorf("raw = %q", stream.raw)
+	}
+}
+
+func TestReadClaudeStreamError(t *testing.T) {
+	broken := errors.New("pipe broke")
+	out := io.MultiReader(strings.NewReader(`{"type":"system","session_id":"s1"}`+"\n"), iotest.ErrReader(broken))
+
+	stream, _, err := readStream(t, out)
+	if !errors.Is(err, broken) {
+		t.Errorf("err = %v, want the read error", err)
+	}
+	if stream.sessionID != "s1" {
+		t.Errorf("sessionID = %q, want what was read before the error", stream.sessionID)
+	}
+}
+
+func TestClaudeQueryStream(t *testing.T) {
+	installFakeCLI(t, "claude")
+
+	// Replace the fake CLI with one that prints canned stream-json
+	out := filepath.Join(t.TempDir(), "stream.jsonl")
+	if err := os.WriteFile(out, []byte(claudeStreamOutput), 0644); err != nil {
+		t.Fatal(err)
+	}
+	bin, err := exec.LookPath("claude")
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\ncat > /dev/null\ncat \"$QRY_FAKE_STREAM\"\n"
+	if err := os.WriteFile(bin, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("QRY_FAKE_STREAM", out)
+
+	events, err := (&Claude{}).QueryStream(context.Background(), "prompt", t.TempDir(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var texts []string
+	res, err := Collect(events, func(ev Event) {
+		if ev.Type == EventText {
+			texts = append(texts, ev.Text)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Response != "SELECT 1" || res.SessionID != "s1" || res.Usage.InputTokens != 15 {
+		t.Errorf("result = %+v", res)
+	}
+	if got := strings.Join(texts, ""); got != "SELECT 1" {
+		t.Errorf("streamed text = %q", got)
+	}
+}
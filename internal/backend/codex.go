@@ -17,38 +17,83 @@ func (c *Codex) InstallCmd() string {
 }
 
 func (c *Codex) Available() bool {
-	_, err := exec.LookPath("codex")
+	_, err := exec.LookPath(ConfigFor("codex").binary("codex"))
 	return err == nil
 }
 
+// codexModels are the models the CLI accepts with --model. Newer ones can
+// be added with backends.codex.models.
+var codexModels = []string{
+	"gpt-5", "gpt-5-codex", "gpt-5-mini",
+	"gpt-4.1", "gpt-4.1-mini", "gpt-4o", "gpt-4o-mini",
+	"o3", "o4-mini", "codex-mini-latest",
+}
+
+// Models returns codexModels, or backends.codex.models
+func (c *Codex) Models(ctx context.Context) ([]string, error) {
+	return ConfigFor("codex").models(codexModels), nil
+}
+
+// Health checks the CLI runs. Auth shows up in the round trip.
+func (c *Codex) Health(ctx context.Context) (Health, error) {
+	return cliVersion(ctx, "codex", ConfigFor("codex").binary("codex"))
+}
+
 // codexJSONResponse represents the JSON output from codex CLI
 type codexJSONResponse struct {
 	SessionID string `json:"session_id"`
 	RolloutID string `json:"rollout_id"`
 	Result    string `json:"result"`
 	Response  string `json:"response"`
+	cliUsageFields
 }
 
 func (c *Codex) Query(ctx context.Context, prompt string, workDir string, opts Options) (Result, error) {
+	cfg := ConfigFor("codex")
+	sb := SandboxFor("codex")
+	if err := sb.Check(); err != nil {
+		return Result{}, fmt.Errorf("codex: %w", err)
+	}
+	repo := workDir
+	workDir, err := cfg.dir(repo)
+	if err != nil {
+		return Result{}, fmt.Errorf("codex: %w", err)
+	}
+
 	var args []string
 
-	// Codex uses different syntax for resume: `codex exec resume <id> "prompt"`
+	// Codex uses different syntax for resume: `codex exec resume <id> <prompt>`.
+	// A prompt of "-" makes codex read it from stdin.
 	if opts.SessionID != "" {
-		args = []string{"exec", "resume", opts.SessionID, prompt, "--output-format", "json"}
+		args = []string{"exec", "resume", opts.SessionID, "-", "--output-format", "json"}
 	} else {
-		args = []string{"exec", "-p", prompt, "--output-format", "json"}
+		args = []string{"exec", "-", "--output-format", "json"}
 	}
 
 	if opts.Model != "" {
 		args = append(args, "--model", opts.Model)
 	}
 
-	cmd := exec.CommandContext(ctx, "codex", args...)
-	cmd.Dir = workDir
+	// Codex enforces read-only itself; it can still read anything
+	if sb.Enabled() {
+		args = append(args, "--sandbox", "read-only")
+		for _, dir := range sb.ExtraDirs(workDir) {
+			args = append(args, "--add-dir", dir)
+		}
+	}
+	for _, dir := range schemaDirs(repo, sb.Dir(workDir)) {
+		args = append(args, "--add-dir", dir)
+	}
+
+	cmd := exec.CommandContext(ctx, cfg.binary("codex"), args...)
+	cmd.Dir = sb.Dir(workDir)
+	cmd.Env = sb.Environ("codex", "OPENAI_", "AZURE_OPENAI_")
+	cmd.Stdin = strings.NewReader(prompt)
+	cfg.apply(cmd)
 
 	out, err := cmd.CombinedOutput()
 	if err != nil {
-		return Result{}, fmt.Errorf("codex: %w\n%s", err, string(out))
+		return Result{}, cliError(ctx, "codex", err, out)
 	}
 
 	// Try to parse JSON response
@@ -76,5 +121,26 @@ func (c *Codex) Query(ctx context.Context, prompt string, workDir string, opts O
 	return Result{
 		Response:  response,
 		SessionID: sessionID,
+		Usage:     resp.usage(),
 	}, nil
 }
+
+// cliUsageFields picks up usage from CLIs that report it in the common
+// input_tokens/output_tokens shape (absent fields stay zero)
+type cliUsageFields struct {
+	TotalCostUSD float64 `json:"total_cost_usd"`
+	NumTurns     int     `json:"num_turns"`
+	Usage        struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+func (f cliUsageFields) usage() Usage {
+	return Usage{
+		InputTokens:  f.Usage.InputTokens,
+		OutputTokens: f.Usage.OutputTokens,
+		CostUSD:      f.TotalCostUSD,
+		Turns:        f.NumTurns,
+	}
+}
@@ -0,0 +1,151 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultTimeout applies when neither the backend nor `timeout` sets one
+const DefaultTimeout = 2 * time.Minute
+
+// Config holds the per-backend settings under `backends.<name>`:
+//
+//	backends:
+//	  cursor:
+//	    model: auto
+//	    timeout: 5m
+//	    binary: cursor              # instead of cursor-agent
+//	    extra_args: ["--fast"]
+//	    env: {CURSOR_API_URL: https://cursor.internal}
+//	    workdir: services/billing   # the CLI runs here instead of the repo root
+//	    models: [auto, gpt-5]       # replaces the built-in list for -m
+//
+// Backend-specific keys (base_url, host, type: exec, ...) live alongside.
+type Config struct {
+	Model     string            `mapstructure:"model"`
+	Timeout   time.Duration     `mapstructure:"timeout"`
+	Binary    string            `mapstructure:"binary"`
+	ExtraArgs []string          `mapstructure:"extra_args"`
+	Env       map[string]string `mapstructure:"env"`
+	WorkDir   string            `mapstructure:"workdir"`
+	Models    []string          `mapstructure:"models"`
+}
+
+// ConfigFor reads `backends.<name>`. Missing keys are zero.
+func ConfigFor(name string) Config {
+	var cfg Config
+	_ = viper.UnmarshalKey("backends."+name, &cfg)
+	return cfg
+}
+
+// models returns the configured model list, or the built-in one
+func (c Config) models(builtin []string) []string {
+	if len(c.Models) > 0 {
+		return c.Models
+	}
+	return builtin
+}
+
+// ModelFor returns the backend's own default model:
+// backends.<name>.model, then defaults.<name>
+func ModelFor(name string) string {
+	if m := ConfigFor(name).Model; m != "" {
+		return m
+	}
+	return viper.GetString("defaults." + name)
+}
+
+// TimeoutFor returns backends.<name>.timeout, then `timeout`, then
+// DefaultTimeout
+func TimeoutFor(name string) time.Duration {
+	if t := ConfigFor(name).Timeout; t > 0 {
+		return t
+	}
+	if t := viper.GetDuration("timeout"); t > 0 {
+		return t
+	}
+	return DefaultTimeout
+}
+
+// SchemaPaths returns `schema_paths`: the files and directories that
+// define the database schema, relative to the repo root
+//
+//	schema_paths: [db/migrations, prisma/schema.prisma, internal/models]
+func SchemaPaths() []string {
+	return viper.GetStringSlice("schema_paths")
+}
+
+// schemaDirs returns the directories holding schema_paths that an agent
+// started in agentDir can't already see, for CLIs with --add-dir. The
+// paths are relative to the repo root, whatever the backend's workdir.
+func schemaDirs(repo, agentDir string) []string {
+	var dirs []string
+	for _, p := range SchemaPaths() {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(repo, p)
+		}
+		info, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			p = filepath.Dir(p)
+		}
+		if rel, err := filepath.Rel(agentDir, p); err == nil && !strings.HasPrefix(rel, "..") {
+			continue // Already inside the agent's directory
+		}
+		if !contains(dirs, p) {
+			dirs = append(dirs, p)
+		}
+	}
+	return dirs
+}
+
+// binary returns the configured executable for a backend, or def
+func (c Config) binary(def string) string {
+	if c.Binary != "" {
+		return c.Binary
+	}
+	return def
+}
+
+// dir resolves `workdir` against the repo root. It's where the CLI runs,
+// not a schema path: that's schema_paths. Like sandbox.dirs, it must
+// stay inside the repo, so the sandbox still confines the agent to it.
+func (c Config) dir(workDir string) (string, error) {
+	if c.WorkDir == "" {
+		return workDir, nil
+	}
+
+	dir := c.WorkDir
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(workDir, dir)
+	}
+	if rel, err := filepath.Rel(workDir, dir); err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("workdir: %q is outside the repository", c.WorkDir)
+	}
+	return dir, nil
+}
+
+// apply adds extra args and env vars to a command. Env vars are set on
+// top of the sandbox's environment (or the full one if it's off).
+func (c Config) apply(cmd *exec.Cmd) {
+	cmd.Args = append(cmd.Args, c.ExtraArgs...)
+
+	if len(c.Env) == 0 {
+		return
+	}
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	// Config keys come back lowercased; env var names are uppercase
+	for k, v := range c.Env {
+		cmd.Env = append(cmd.Env, strings.ToUpper(k)+"="+v)
+	}
+}
@@ -0,0 +1,75 @@
+package backend
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestWorkdirKeepsSchemaPathsAtRepoRoot(t *testing.T) {
+	out := installFakeCLI(t, "claude")
+
+	repo := t.TempDir()
+	for _, dir := range []string{"db", "services/billing"} {
+		if err := os.MkdirAll(filepath.Join(repo, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	viper.Set("backends.claude.workdir", "services/billing")
+	viper.Set("schema_paths", []string{"db"})
+	defer viper.Set("backends.claude.workdir", "")
+	defer viper.Set("schema_paths", nil)
+
+	if _, err := (&Claude{}).Query(context.Background(), "q", repo, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	args := readFile(t, filepath.Join(out, "args"))
+	if want := "--add-dir\n" + filepath.Join(repo, "db") + "\n"; !strings.Contains(args, want) {
+		t.Errorf("args don't add the repo's db/:\n%s", args)
+	}
+}
+
+func TestWorkdirStaysInRepo(t *testing.T) {
+	repo := t.TempDir()
+
+	tests := []struct {
+		workdir string
+		want    string // Empty when rejected
+	}{
+		{"", repo},
+		{"services/billing", filepath.Join(repo, "services/billing")},
+		{"services/../db", filepath.Join(repo, "db")},
+		{filepath.Join(repo, "db"), filepath.Join(repo, "db")},
+		{"..", ""},
+		{"../other", ""},
+		{"services/../../other", ""},
+		{filepath.Dir(repo), ""},
+		{"/etc", ""},
+	}
+	for _, tt := range tests {
+		got, err := Config{WorkDir: tt.workdir}.dir(repo)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("dir(%q) = %q, want it rejected", tt.workdir, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("dir(%q) = %q, %v; want %q", tt.workdir, got, err, tt.want)
+		}
+	}
+
+	// Backends refuse to run outside the repo
+	installFakeCLI(t, "claude")
+	viper.Set("backends.claude.workdir", "..")
+	defer viper.Set("backends.claude.workdir", "")
+	if _, err := (&Claude{}).Query(context.Background(), "q", repo, Options{}); err == nil || !strings.Contains(err.Error(), "outside the repository") {
+		t.Errorf("Query err = %v, want the workdir rejected", err)
+	}
+}
@@ -17,13 +17,36 @@ func (c *Cursor) InstallCmd() string {
 }
 
 func (c *Cursor) Available() bool {
-	// Try cursor-agent first (newer CLI name)
+	_, err := exec.LookPath(c.binary())
+	return err == nil
+}
+
+// cursorModels are the models the CLI accepts with --model. Newer ones
+// can be added with backends.cursor.models.
+var cursorModels = []string{
+	"auto", "gpt-5", "sonnet-4", "sonnet-4-thinking", "opus-4.1", "grok",
+}
+
+// Models returns cursorModels, or backends.cursor.models
+func (c *Cursor) Models(ctx context.Context) ([]string, error) {
+	return ConfigFor("cursor").models(cursorModels), nil
+}
+
+// binary prefers the configured binary, then cursor-agent (newer CLI
+// name), then cursor
+func (c *Cursor) binary() string {
+	if b := ConfigFor("cursor").Binary; b != "" {
+		return b
+	}
 	if _, err := exec.LookPath("cursor-agent"); err == nil {
-		return true
+		return "cursor-agent"
 	}
-	// Fall back to cursor
-	_, err := exec.LookPath("cursor")
-	return err == nil
+	return "cursor"
+}
+
+// Health checks the CLI runs. Auth shows up in the round trip.
+func (c *Cursor) Health(ctx context.Context) (Health, error) {
+	return cliVersion(ctx, "cursor", c.binary())
 }
 
 // cursorJSONResponse represents the JSON output from cursor CLI
@@ -32,16 +55,36 @@ type cursorJSONResponse struct {
 	ChatID    string `json:"chat_id"`
 	Result    string `json:"result"`
 	Response  string `json:"response"`
+	cliUsageFields
 }
 
 func (c *Cursor) Query(ctx context.Context, prompt string, workDir string, opts Options) (Result, error) {
-	// Determine which CLI binary to use
-	cliCmd := "cursor"
-	if _, err := exec.LookPath("cursor-agent"); err == nil {
-		cliCmd = "cursor-agent"
+	cfg := ConfigFor("cursor")
+	sb := SandboxFor("cursor")
+	if err := sb.Check(); err != nil {
+		return Result{}, fmt.Errorf("cursor: %w", err)
 	}
+	repo := workDir
+	workDir, err := cfg.dir(workDir)
+	if err != nil {
+		return Result{}, fmt.Errorf("cursor: %w", err)
+	}
+	dir := sb.Dir(workDir)
 
-	args := []string{"-p", prompt, "--output-format", "json"}
+	// cursor doesn't read prompts from stdin; keep it out of argv with a
+	// file in the repo's .qry/, which init gitignores
+	path, cleanup, err := writePromptFile(repo, prompt)
+	if err != nil {
+		return Result{}, fmt.Errorf("cursor: writing prompt: %w", err)
+	}
+	defer cleanup()
+
+	args := []string{"-p", filePrompt(dir, path), "--output-format", "json"}
+
+	// Ask mode answers questions without editing files or running commands
+	if sb.Enabled() {
+		args = append(args, "--mode", "ask")
+	}
 
 	if opts.SessionID != "" {
 		args = append(args, "--resume", opts.SessionID)
@@ -51,12 +94,14 @@ func (c *Cursor) Query(ctx context.Context, prompt string, workDir string, opts
 		args = append(args, "--model", opts.Model)
 	}
 
-	cmd := exec.CommandContext(ctx, cliCmd, args...)
-	cmd.Dir = workDir
+	cmd := exec.CommandContext(ctx, c.binary(), args...)
+	cmd.Dir = dir
+	cmd.Env = sb.Environ("cursor")
+	cfg.apply(cmd)
 
 	out, err := cmd.CombinedOutput()
 	if err != nil {
-		return Result{}, fmt.Errorf("cursor: %w\n%s", err, string(out))
+		return Result{}, cliError(ctx, "cursor", err, out)
 	}
 
 	// Try to parse JSON response
@@ -84,5 +129,6 @@ func (c *Cursor) Query(ctx context.Context, prompt string, workDir string, opts
 	return Result{
 		Response:  response,
 		SessionID: sessionID,
+		Usage:     resp.usage(),
 	}, nil
 }
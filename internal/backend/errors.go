@@ -0,0 +1,144 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+)
+
+// ErrorKind classifies why a backend call failed
+type ErrorKind string
+
+const (
+	KindNotInstalled ErrorKind = "not_installed"
+	KindAuth         ErrorKind = "auth"
+	KindRateLimit    ErrorKind = "rate_limit"
+	KindTimeout      ErrorKind = "timeout"
+	KindMalformed    ErrorKind = "malformed_output"
+	KindCanceled     ErrorKind = "canceled"
+	KindUnknown      ErrorKind = "unknown"
+)
+
+// Error is a classified backend failure. The message is unchanged from
+// the underlying error so users still see the CLI output.
+type Error struct {
+	Backend string
+	Kind    ErrorKind
+	Err     error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Retryable reports whether calling the same backend again may succeed
+func (e *Error) Retryable() bool {
+	return e.Kind == KindRateLimit
+}
+
+// KindOf returns the classification of err, or KindUnknown
+func KindOf(err error) ErrorKind {
+	var be *Error
+	if errors.As(err, &be) {
+		return be.Kind
+	}
+	if errors.Is(err, context.Canceled) {
+		return KindCanceled
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return KindTimeout
+	}
+	return KindUnknown
+}
+
+// Output fragments that identify auth and rate-limit failures across CLIs
+var (
+	authMarkers = []string{
+		"not logged in", "please log in", "please login", "run /login",
+		"unauthorized", "invalid api key", "invalid x-api-key",
+		"authentication_error", "authentication failed", "authentication required",
+		"api key not found", "missing api key", "status 401", "401 unauthorized",
+		"expired token", "token expired", "credentials",
+	}
+	rateLimitMarkers = []string{
+		"rate limit", "rate_limit", "ratelimit", "too many requests",
+		"status 429", "429 too many", "quota", "overloaded", "usage limit",
+	}
+)
+
+// cliError builds a classified error for a failed CLI run, keeping the
+// "<backend>: <err>\n<output>" message format
+func cliError(ctx context.Context, name string, err error, out []byte) error {
+	return &Error{
+		Backend: name,
+		Kind:    classify(ctx, err, string(out)),
+		Err:     fmt.Errorf("%s: %w\n%s", name, err, string(out)),
+	}
+}
+
+// httpError builds a classified error for a non-2xx HTTP response
+func httpError(name string, status int, statusText string, body []byte) error {
+	kind := KindUnknown
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		kind = KindAuth
+	case status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable || status == 529:
+		kind = KindRateLimit
+	case status == http.StatusGatewayTimeout || status == http.StatusRequestTimeout:
+		kind = KindTimeout
+	}
+
+	return &Error{
+		Backend: name,
+		Kind:    kind,
+		Err:     fmt.Errorf("%s: %s\n%s", name, statusText, string(body)),
+	}
+}
+
+// malformedError reports output that couldn't be parsed
+func malformedError(name string, err error) error {
+	return &Error{Backend: name, Kind: KindMalformed, Err: err}
+}
+
+// transportError classifies errors from the call itself (no output)
+func transportError(ctx context.Context, name string, err error) error {
+	return &Error{
+		Backend: name,
+		Kind:    classify(ctx, err, ""),
+		Err:     fmt.Errorf("%s: %w", name, err),
+	}
+}
+
+func classify(ctx context.Context, err error, output string) ErrorKind {
+	if errors.Is(err, exec.ErrNotFound) {
+		return KindNotInstalled
+	}
+
+	// A killed process reports "signal: killed"; the context tells us why
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if errors.Is(ctxErr, context.Canceled) {
+			return KindCanceled
+		}
+		return KindTimeout
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return KindTimeout
+	}
+
+	lower := strings.ToLower(output + " " + err.Error())
+	for _, m := range authMarkers {
+		if strings.Contains(lower, m) {
+			return KindAuth
+		}
+	}
+	for _, m := range rateLimitMarkers {
+		if strings.Contains(lower, m) {
+			return KindRateLimit
+		}
+	}
+
+	return KindUnknown
+}
@@ -0,0 +1,85 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	bg := context.Background()
+	failed := errors.New("exit status 1")
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		err    error
+		output string
+		want   ErrorKind
+	}{
+		{"missing binary", bg, &exec.Error{Name: "claude", Err: exec.ErrNotFound}, "", KindNotInstalled},
+		{"canceled", canceled, errors.New("signal: killed"), "", KindCanceled},
+		{"timed out", expired, errors.New("signal: killed"), "", KindTimeout},
+		{"deadline error", bg, fmt.Errorf("dial: %w", context.DeadlineExceeded), "", KindTimeout},
+		{"not logged in", bg, failed, "Error: Not logged in. Please run /login", KindAuth},
+		{"auth in error", bg, errors.New("401 Unauthorized"), "", KindAuth},
+		{"rate limit", bg, failed, "429 Too Many Requests", KindRateLimit},
+		{"quota", bg, failed, "You have exceeded your quota", KindRateLimit},
+		{"other", bg, failed, "syntax error", KindUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classify(tt.ctx, tt.err, tt.output); got != tt.want {
+				t.Errorf("classify = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{"classified", &Error{Kind: KindAuth, Err: errors.New("x")}, KindAuth},
+		{"wrapped", fmt.Errorf("query: %w", &Error{Kind: KindRateLimit, Err: errors.New("x")}), KindRateLimit},
+		{"canceled", fmt.Errorf("query: %w", context.Canceled), KindCanceled},
+		{"deadline", context.DeadlineExceeded, KindTimeout},
+		{"plain", errors.New("x"), KindUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KindOf(tt.err); got != tt.want {
+				t.Errorf("KindOf = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPError(t *testing.T) {
+	tests := []struct {
+		status int
+		want   ErrorKind
+	}{
+		{401, KindAuth},
+		{403, KindAuth},
+		{429, KindRateLimit},
+		{503, KindRateLimit},
+		{529, KindRateLimit},
+		{504, KindTimeout},
+		{500, KindUnknown},
+	}
+	for _, tt := range tests {
+		if got := KindOf(httpError("api", tt.status, "status", nil)); got != tt.want {
+			t.Errorf("httpError(%d) kind = %s, want %s", tt.status, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ExecConfig declares a CLI backend in .qry.yaml:
+//
+//	backends:
+//	  opencode:
+//	    type: exec
+//	    command: opencode
+//	    args: ["run", "--format", "json", "{{prompt}}"]
+//	    resume_args: ["run", "--session", "{{session}}", "--format", "json", "{{prompt}}"]
+//	    model_args: ["--model", "{{model}}"]
+//	    result_path: result
+//	    session_path: session_id
+//	    models: [anthropic/claude-sonnet-4, openai/*]
+//	    sandbox_args: ["--read-only"]
+//
+// Args support {{prompt}}, {{prompt_file}}, {{session}}, {{model}} and
+// {{workdir}}. Prefer `stdin: true` or {{prompt_file}} over {{prompt}}:
+// anything in argv is visible to other users in `ps`. Without args the
+// prompt is written to stdin.
+type ExecConfig struct {
+	Type        string   `mapstructure:"type"`
+	Command     string   `mapstructure:"command"`
+	Args        []string `mapstructure:"args"`
+	ResumeArgs  []string `mapstructure:"resume_args"`
+	ModelArgs   []string `mapstructure:"model_args"`
+	ResultPath  string   `mapstructure:"result_path"`
+	SessionPath string   `mapstructure:"session_path"`
+	Install     string   `mapstructure:"install"`
+	Models      []string `mapstructure:"models"`       // For `qry models` and -m validation
+	Stdin       bool     `mapstructure:"stdin"`        // Write the prompt to stdin
+	SandboxArgs []string `mapstructure:"sandbox_args"` // Appended unless the sandbox is off
+}
+
+// Exec is a backend defined entirely in config
+type Exec struct {
+	name string
+	cfg  ExecConfig
+}
+
+// NewExec creates a config-driven backend
+func NewExec(name string, cfg ExecConfig) (*Exec, error) {
+	if cfg.Command == "" {
+		return nil, fmt.Errorf("backends.%s: command required", name)
+	}
+	// With no args the prompt goes to stdin, never argv
+	if len(cfg.Args) == 0 {
+		cfg.Stdin = true
+	}
+	if !cfg.Stdin && !containsPlaceholder(cfg.Args, "{{prompt}}") && !containsPlaceholder(cfg.Args, "{{prompt_file}}") {
+		return nil, fmt.Errorf("backends.%s: args must include {{prompt}} or {{prompt_file}}, or set stdin: true", name)
+	}
+	if len(cfg.ResumeArgs) > 0 && !containsPlaceholder(cfg.ResumeArgs, "{{session}}") {
+		return nil, fmt.Errorf("backends.%s: resume_args must include {{session}}", name)
+	}
+	return &Exec{name: name, cfg: cfg}, nil
+}
+
+func (e *Exec) Name() string { return e.name }
+
+func (e *Exec) InstallCmd() string {
+	if e.cfg.Install != "" {
+		return e.cfg.Install
+	}
+	return "install " + e.cfg.Command + " and make sure it is on PATH"
+}
+
+func (e *Exec) Available() bool {
+	_, err := exec.LookPath(e.cfg.Command)
+	return err == nil
+}
+
+// Models returns the models declared in config, if any
+func (e *Exec) Models(ctx context.Context) ([]string, error) {
+	return e.cfg.Models, nil
+}
+
+func (e *Exec) Query(ctx context.Context, prompt string, workDir string, opts Options) (Result, error) {
+	cfg := ConfigFor(e.name)
+	sb := SandboxFor(e.name)
+	if err := sb.Check(); err != nil {
+		return Result{}, fmt.Errorf("%s: %w", e.name, err)
+	}
+	repo := workDir
+	dir, err := cfg.dir(workDir)
+	if err != nil {
+		return Result{}, fmt.Errorf("%s: %w", e.name, err)
+	}
+	workDir = sb.Dir(dir)
+
+	// Without resume_args the CLI has no multi-turn support; always start fresh
+	tmpl := e.cfg.Args
+	if opts.SessionID != "" && len(e.cfg.ResumeArgs) > 0 {
+		tmpl = e.cfg.ResumeArgs
+	}
+
+	var promptFile string
+	if containsPlaceholder(tmpl, "{{prompt_file}}") {
+		path, cleanup, err := writePromptFile(repo, prompt)
+		if err != nil {
+			return Result{}, fmt.Errorf("%s: writing prompt: %w", e.name, err)
+		}
+		defer cleanup()
+		promptFile = path
+	}
+
+	// Single-pass replacer: placeholders inside the prompt are left alone
+	vars := strings.NewReplacer(
+		"{{prompt_file}}", promptFile,
+		"{{prompt}}", prompt,
+		"{{session}}", opts.SessionID,
+		"{{model}}", opts.Model,
+		"{{workdir}}", workDir,
+	)
+
+	args := expandArgs(tmpl, vars)
+	if opts.Model != "" {
+		args = append(args, expandArgs(e.cfg.ModelArgs, vars)...)
+	}
+	if sb.Enabled() {
+		args = append(args, expandArgs(e.cfg.SandboxArgs, vars)...)
+	}
+
+	cmd := exec.CommandContext(ctx, e.cfg.Command, args...)
+	cmd.Dir = workDir
+	cmd.Env = sb.Environ(e.name)
+	cfg.apply(cmd)
+	if e.cfg.Stdin {
+		cmd.Stdin = strings.NewReader(prompt)
+	}
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return Result{}, cliError(ctx, e.name, err, out)
+	}
+
+	if e.cfg.ResultPath == "" {
+		return Result{Response: strings.TrimSpace(string(out))}, nil
+	}
+
+	doc, ok := findJSON(out, e.cfg.ResultPath)
+	if !ok {
+		return Result{}, malformedError(e.name, fmt.Errorf("%s: no %q field in output\n%s", e.name, e.cfg.ResultPath, string(out)))
+	}
+
+	response, _ := lookupPath(doc, e.cfg.ResultPath)
+
+	// Session ID may live in an earlier line of JSONL output
+	var sessionID string
+	if e.cfg.SessionPath != "" && len(e.cfg.ResumeArgs) > 0 {
+		if sessionID, _ = lookupPath(doc, e.cfg.SessionPath); sessionID == "" {
+			if sdoc, ok := findJSON(out, e.cfg.SessionPath); ok {
+				sessionID, _ = lookupPath(sdoc, e.cfg.SessionPath)
+			}
+		}
+	}
+
+	return Result{
+		Response:  strings.TrimSpace(response),
+		SessionID: sessionID,
+	}, nil
+}
+
+func containsPlaceholder(args []string, placeholder string) bool {
+	for _, a := range args {
+		if strings.Contains(a, placeholder) {
+			return true
+		}
+	}
+	return false
+}
+
+func expandArgs(tmpl []string, vars *strings.Replacer) []string {
+	args := make([]string, 0, len(tmpl))
+	for _, a := range tmpl {
+		args = append(args, vars.Replace(a))
+	}
+	return args
+}
+
+// findJSON parses output as a single JSON document, or as JSON lines
+// (newest first), returning the first document where path resolves
+func findJSON(out []byte, path string) (any, bool) {
+	var doc any
+	if err := json.Unmarshal(out, &doc); err == nil {
+		if _, ok := lookupPath(doc, path); ok {
+			return doc, true
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		var ldoc any
+		if err := json.Unmarshal([]byte(lines[i]), &ldoc); err != nil {
+			continue
+		}
+		if _, ok := lookupPath(ldoc, path); ok {
+			return ldoc, true
+		}
+	}
+
+	return nil, false
+}
+
+// lookupPath resolves a dotted path like "result" or "messages.-1.content".
+// Numeric segments index arrays; negative indexes count from the end.
+func lookupPath(doc any, path string) (string, bool) {
+	cur := doc
+	for _, seg := range strings.Split(path, ".") {
+		switch v := cur.(type) {
+		case map[string]any:
+			next, ok := v[seg]
+			if !ok {
+				return "", false
+			}
+			cur = next
+		case []any:
+			idx, err := strconv.Atoi(seg)
+			if err != nil {
+				return "", false
+			}
+			if idx < 0 {
+				idx += len(v)
+			}
+			if idx < 0 || idx >= len(v) {
+				return "", false
+			}
+			cur = v[idx]
+		default:
+			return "", false
+		}
+	}
+
+	switch v := cur.(type) {
+	case string:
+		return v, true
+	case nil:
+		return "", false
+	default:
+		b, _ := json.Marshal(v)
+		return string(b), true
+	}
+}
+
+// LoadConfigured registers exec backends declared under `backends:` in
+// .qry.yaml. Invalid definitions are skipped and reported together.
+func LoadConfigured() error {
+	var errs []string
+
+	var names []string
+	for name := range viper.GetStringMap("backends") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		key := "backends." + name
+		if viper.GetString(key+".type") != "exec" {
+			continue
+		}
+
+		if isBuiltin(name) {
+			errs = append(errs, fmt.Sprintf("%s: cannot redefine built-in backend", key))
+			continue
+		}
+
+		var cfg ExecConfig
+		if err := viper.UnmarshalKey(key, &cfg); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", key, err))
+			continue
+		}
+
+		b, err := NewExec(name, cfg)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		Register(name, b)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
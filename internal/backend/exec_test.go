@@ -0,0 +1,200 @@
+package backend
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestNewExec(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     ExecConfig
+		stdin   bool
+		wantErr string
+	}{
+		{"no command", ExecConfig{}, false, "command required"},
+		{"stdin by default", ExecConfig{Command: "mycli"}, true, ""},
+		{"prompt arg", ExecConfig{Command: "mycli", Args: []string{"run", "{{prompt}}"}}, false, ""},
+		{"prompt file", ExecConfig{Command: "mycli", Args: []string{"--file", "{{prompt_file}}"}}, false, ""},
+		{"stdin with args", ExecConfig{Command: "mycli", Args: []string{"run"}, Stdin: true}, true, ""},
+		{"args without prompt", ExecConfig{Command: "mycli", Args: []string{"run"}}, false, "args must include"},
+		{"resume without session", ExecConfig{Command: "mycli", ResumeArgs: []string{"resume"}}, false, "resume_args must include"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := NewExec("mycli", tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.cfg.Stdin != tt.stdin {
+				t.Errorf("stdin = %v, want %v", e.cfg.Stdin, tt.stdin)
+			}
+			if tt.cfg.Args == nil && len(e.cfg.Args) > 0 {
+				t.Errorf("args = %q, want the prompt kept out of argv", e.cfg.Args)
+			}
+		})
+	}
+}
+
+func TestExecQuery(t *testing.T) {
+	out := installFakeCLI(t, "mycli")
+	workDir := t.TempDir()
+
+	e, err := NewExec("mycli", ExecConfig{
+		Command:     "mycli",
+		Args:        []string{"run", "--dir", "{{workdir}}", "{{prompt}}"},
+		ResumeArgs:  []string{"resume", "{{session}}", "{{prompt}}"},
+		ModelArgs:   []string{"--model", "{{model}}"},
+		ResultPath:  "result",
+		SessionPath: "session_id",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Placeholders inside the prompt are passed through untouched
+	res, err := e.Query(context.Background(), "ask {{model}}", workDir, Options{Model: "m1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Response != "SELECT 1" || res.SessionID != "s1" {
+		t.Errorf("result = %+v, want the reply and session", res)
+	}
+	want := strings.Join([]string{"run", "--dir", workDir, "ask {{model}}", "--model", "m1"}, "\n") + "\n"
+	if got := readFile(t, filepath.Join(out, "args")); got != want {
+		t.Errorf("args =\n%s\nwant\n%s", got, want)
+	}
+
+	if _, err := e.Query(context.Background(), "more", workDir, Options{SessionID: "s1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readFile(t, filepath.Join(out, "args")), "resume\ns1\nmore\n"; got != want {
+		t.Errorf("resume args = %q, want %q", got, want)
+	}
+
+	plain, err := NewExec("mycli", ExecConfig{Command: "mycli"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := plain.Query(context.Background(), "on stdin", workDir, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, filepath.Join(out, "stdin")); got != "on stdin" {
+		t.Errorf("stdin = %q, want the prompt", got)
+	}
+	if got := readFile(t, filepath.Join(out, "args")); strings.TrimSpace(got) != "" {
+		t.Errorf("args = %q, want none", got)
+	}
+}
+
+func TestLookupPath(t *testing.T) {
+	doc := map[string]any{
+		"result": "SELECT 1",
+		"count":  float64(2),
+		"empty":  nil,
+		"messages": []any{
+			map[string]any{"content": "first"},
+			map[string]any{"content": "last"},
+		},
+		"meta": map[string]any{"session": map[string]any{"id": "s1"}},
+	}
+
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{"result", "SELECT 1", true},
+		{"meta.session.id", "s1", true},
+		{"messages.0.content", "first", true},
+		{"messages.-1.content", "last", true},
+		{"messages.2.content", "", false},
+		{"messages.-3.content", "", false},
+		{"messages.x", "", false},
+		{"count", "2", true},
+		{"meta.session", `{"id":"s1"}`, true},
+		{"empty", "", false},
+		{"missing", "", false},
+		{"result.deeper", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := lookupPath(doc, tt.path)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("lookupPath(%q) = %q, %v; want %q, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFindJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		path string
+		want string
+		ok   bool
+	}{
+		{"single document", `{"result": "a"}`, "result", "a", true},
+		{"pretty printed", "{\n  \"result\": \"a\"\n}", "result", "a", true},
+		{"lines, newest first", "{\"result\": \"a\"}\n{\"result\": \"b\"}\n", "result", "b", true},
+		{"skips lines without the path", "{\"result\": \"a\"}\n{\"type\": \"done\"}", "result", "a", true},
+		{"skips non-JSON lines", "loading...\n{\"result\": \"a\"}\nbye", "result", "a", true},
+		{"not found", "{\"type\": \"done\"}", "result", "", false},
+		{"not JSON", "SELECT 1", "result", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, ok := findJSON([]byte(tt.out), tt.path)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if got, _ := lookupPath(doc, tt.path); got != tt.want {
+				t.Errorf("found %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigured(t *testing.T) {
+	viper.Set("backends", map[string]any{
+		"mycli":  map[string]any{"type": "exec", "command": "mycli", "stdin": true},
+		"broken": map[string]any{"type": "exec"},
+		"claude": map[string]any{"type": "exec", "command": "other"},
+		"codex":  map[string]any{"model": "gpt-5"},
+	})
+	defer viper.Set("backends", nil)
+	defer delete(registry, "mycli")
+
+	err := LoadConfigured()
+	if err == nil {
+		t.Fatal("want an error for the invalid definitions")
+	}
+	for _, want := range []string{"backends.broken: command required", "backends.claude: cannot redefine built-in backend"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want %q", err, want)
+		}
+	}
+
+	b, err := Get("mycli")
+	if err != nil {
+		t.Fatalf("valid backend not registered: %v", err)
+	}
+	if e, ok := b.(*Exec); !ok || e.cfg.Command != "mycli" || !e.cfg.Stdin {
+		t.Errorf("mycli = %+v", b)
+	}
+	if _, ok := registry["broken"]; ok {
+		t.Error("broken backend registered")
+	}
+	if _, ok := registry["claude"].(*Claude); !ok {
+		t.Error("claude was replaced")
+	}
+}
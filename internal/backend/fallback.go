@@ -0,0 +1,121 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Retry controls how Fallback treats each backend in a chain
+type Retry struct {
+	Attempts int                           // Extra attempts on retryable errors (rate limits)
+	Backoff  time.Duration                 // Delay before the first retry, doubled each time
+	Timeout  func(b Backend) time.Duration // Per-attempt timeout (nil or 0 = none)
+}
+
+// Failure describes a failed attempt during Fallback
+type Failure struct {
+	Backend  string
+	Kind     ErrorKind
+	Err      error
+	Retrying bool // Same backend will be tried again
+}
+
+// AttemptFunc performs one query against b. It builds its own prompt and
+// options since sessions and models differ per backend.
+type AttemptFunc func(ctx context.Context, b Backend) (Result, error)
+
+// Fallback tries each backend in order, retrying rate-limited calls with
+// backoff and falling through on any other failure. It stops early if
+// ctx is canceled. Returns the backend that answered.
+func Fallback(ctx context.Context, chain []Backend, retry Retry, attempt AttemptFunc, onFail func(Failure)) (Backend, Result, error) {
+	if len(chain) == 0 {
+		return nil, Result{}, fmt.Errorf("no backends configured")
+	}
+
+	var failures []Failure
+
+	report := func(f Failure) {
+		if !f.Retrying {
+			failures = append(failures, f)
+		}
+		if onFail != nil {
+			onFail(f)
+		}
+	}
+
+	for _, b := range chain {
+		if !b.Available() {
+			report(Failure{
+				Backend: b.Name(),
+				Kind:    KindNotInstalled,
+				Err:     fmt.Errorf("%s not installed", b.Name()),
+			})
+			continue
+		}
+
+		backoff := retry.Backoff
+		for try := 0; ; try++ {
+			var timeout time.Duration
+			if retry.Timeout != nil {
+				timeout = retry.Timeout(b)
+			}
+			result, err := runAttempt(ctx, b, timeout, attempt)
+			if err == nil {
+				return b, result, nil
+			}
+
+			kind := KindOf(err)
+
+			// User abort: don't try anything else
+			if ctx.Err() != nil || kind == KindCanceled {
+				return nil, Result{}, err
+			}
+
+			var be *Error
+			retryable := errors.As(err, &be) && be.Retryable() && try < retry.Attempts
+
+			report(Failure{Backend: b.Name(), Kind: kind, Err: err, Retrying: retryable})
+
+			if !retryable {
+				break
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil, Result{}, ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+	}
+
+	if len(failures) == 1 {
+		return nil, Result{}, failures[0].Err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("all backends failed:")
+	for _, f := range failures {
+		fmt.Fprintf(&sb, "\n  %s (%s): %s", f.Backend, f.Kind, firstLine(f.Err.Error()))
+	}
+	return nil, Result{}, fmt.Errorf("%s", sb.String())
+}
+
+func runAttempt(ctx context.Context, b Backend, timeout time.Duration, attempt AttemptFunc) (Result, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return attempt(ctx, b)
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,178 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingBackend fails its first len(errs) queries with errs in order,
+// then answers
+type failingBackend struct {
+	name    string
+	missing bool
+	errs    []error
+	calls   int
+}
+
+func (f *failingBackend) Name() string       { return f.name }
+func (f *failingBackend) Available() bool    { return !f.missing }
+func (f *failingBackend) InstallCmd() string { return "" }
+
+func (f *failingBackend) Query(ctx context.Context, prompt string, workDir string, opts Options) (Result, error) {
+	f.calls++
+	if f.calls <= len(f.errs) {
+		return Result{}, f.errs[f.calls-1]
+	}
+	return Result{Response: f.name}, nil
+}
+
+func failure(kind ErrorKind) error {
+	return &Error{Kind: kind, Err: errors.New(string(kind))}
+}
+
+func query(ctx context.Context, b Backend) (Result, error) {
+	return b.Query(ctx, "", "", Options{})
+}
+
+func TestFallback(t *testing.T) {
+	retry := Retry{Attempts: 2, Backoff: time.Millisecond}
+
+	t.Run("retries rate limits", func(t *testing.T) {
+		a := &failingBackend{name: "a", errs: []error{failure(KindRateLimit), failure(KindRateLimit)}}
+		b := &failingBackend{name: "b"}
+
+		var fails []Failure
+		got, result, err := Fallback(context.Background(), []Backend{a, b}, retry, query, func(f Failure) {
+			fails = append(fails, f)
+		})
+		if err != nil || got != a || result.Response != "a" {
+			t.Fatalf("Fallback = %v, %q, %v; want a to answer", got, result.Response, err)
+		}
+		if a.calls != 3 || b.calls != 0 {
+			t.Errorf("calls = a:%d b:%d, want a:3 b:0", a.calls, b.calls)
+		}
+		if len(fails) != 2 || !fails[0].Retrying || !fails[1].Retrying {
+			t.Errorf("failures = %+v, want two retries", fails)
+		}
+	})
+
+	t.Run("falls through after the last retry", func(t *testing.T) {
+		limit := failure(KindRateLimit)
+		a := &failingBackend{name: "a", errs: []error{limit, limit, limit}}
+		b := &failingBackend{name: "b"}
+
+		var fails []Failure
+		got, _, err := Fallback(context.Background(), []Backend{a, b}, retry, query, func(f Failure) {
+			fails = append(fails, f)
+		})
+		if err != nil || got != b {
+			t.Fatalf("Fallback = %v, %v; want b to answer", got, err)
+		}
+		if a.calls != 3 {
+			t.Errorf("a calls = %d, want 3", a.calls)
+		}
+		if last := fails[len(fails)-1]; last.Backend != "a" || last.Retrying {
+			t.Errorf("last failure = %+v, want a giving up", last)
+		}
+	})
+
+	t.Run("no retry on auth", func(t *testing.T) {
+		a := &failingBackend{name: "a", errs: []error{failure(KindAuth)}}
+		b := &failingBackend{name: "b"}
+
+		got, _, err := Fallback(context.Background(), []Backend{a, b}, retry, query, nil)
+		if err != nil || got != b {
+			t.Fatalf("Fallback = %v, %v; want b to answer", got, err)
+		}
+		if a.calls != 1 {
+			t.Errorf("a calls = %d, want 1", a.calls)
+		}
+	})
+
+	t.Run("skips missing backends", func(t *testing.T) {
+		a := &failingBackend{name: "a", missing: true}
+		b := &failingBackend{name: "b"}
+
+		var fails []Failure
+		got, _, err := Fallback(context.Background(), []Backend{a, b}, retry, query, func(f Failure) {
+			fails = append(fails, f)
+		})
+		if err != nil || got != b || a.calls != 0 {
+			t.Fatalf("Fallback = %v, %v (a calls %d); want b to answer", got, err, a.calls)
+		}
+		if len(fails) != 1 || fails[0].Kind != KindNotInstalled {
+			t.Errorf("failures = %+v, want a not installed", fails)
+		}
+	})
+
+	t.Run("all fail", func(t *testing.T) {
+		a := &failingBackend{name: "a", errs: []error{failure(KindAuth)}}
+		b := &failingBackend{name: "b", errs: []error{failure(KindMalformed)}}
+
+		_, _, err := Fallback(context.Background(), []Backend{a, b}, retry, query, nil)
+		if err == nil || !strings.Contains(err.Error(), "a (auth)") || !strings.Contains(err.Error(), "b (malformed_output)") {
+			t.Errorf("err = %v, want both failures listed", err)
+		}
+	})
+
+	t.Run("one failure keeps its error", func(t *testing.T) {
+		a := &failingBackend{name: "a", errs: []error{failure(KindAuth)}}
+
+		_, _, err := Fallback(context.Background(), []Backend{a}, retry, query, nil)
+		if KindOf(err) != KindAuth {
+			t.Errorf("err = %v, want the auth error", err)
+		}
+	})
+
+	t.Run("stops when canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		b := &failingBackend{name: "b"}
+		attempt := func(ctx context.Context, _ Backend) (Result, error) {
+			cancel()
+			return Result{}, ctx.Err()
+		}
+
+		_, _, err := Fallback(ctx, []Backend{&failingBackend{name: "a"}, b}, retry, attempt, nil)
+		if !errors.Is(err, context.Canceled) || b.calls != 0 {
+			t.Errorf("err = %v (b calls %d), want canceled before b", err, b.calls)
+		}
+	})
+
+	t.Run("stops when canceled during backoff", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		a := &failingBackend{name: "a", errs: []error{failure(KindRateLimit)}}
+		b := &failingBackend{name: "b"}
+
+		_, _, err := Fallback(ctx, []Backend{a, b}, Retry{Attempts: 1, Backoff: time.Hour}, query, func(f Failure) {
+			cancel()
+		})
+		if !errors.Is(err, context.Canceled) || a.calls != 1 || b.calls != 0 {
+			t.Errorf("err = %v (calls a:%d b:%d), want canceled in backoff", err, a.calls, b.calls)
+		}
+	})
+
+	t.Run("per-attempt timeout", func(t *testing.T) {
+		slow := func(ctx context.Context, b Backend) (Result, error) {
+			if b.Name() == "a" {
+				<-ctx.Done()
+				return Result{}, ctx.Err()
+			}
+			return Result{Response: b.Name()}, nil
+		}
+		timeout := Retry{Timeout: func(Backend) time.Duration { return time.Millisecond }}
+
+		var fails []Failure
+		got, _, err := Fallback(context.Background(), []Backend{&failingBackend{name: "a"}, &failingBackend{name: "b"}}, timeout, slow, func(f Failure) {
+			fails = append(fails, f)
+		})
+		if err != nil || got.Name() != "b" {
+			t.Fatalf("Fallback = %v, %v; want b after a timed out", got, err)
+		}
+		if len(fails) != 1 || fails[0].Kind != KindTimeout {
+			t.Errorf("failures = %+v, want a timing out", fails)
+		}
+	})
+}
@@ -17,7 +17,7 @@ func (g *Gemini) InstallCmd() string {
 }
 
 func (g *Gemini) Available() bool {
-	_, err := exec.LookPath("gemini")
+	_, err := exec.LookPath(ConfigFor("gemini").binary("gemini"))
 	return err == nil
 }
 
@@ -30,7 +30,21 @@ type geminiJSONResponse struct {
 }
 
 func (g *Gemini) Query(ctx context.Context, prompt string, workDir string, opts Options) (Result, error) {
-	args := []string{"-p", prompt, "--output-format", "json"}
+	cfg := ConfigFor("gemini")
+	sb := SandboxFor("gemini")
+	if err := sb.Check(); err != nil {
+		return Result{}, fmt.Errorf("gemini: %w", err)
+	}
+	workDir, err := cfg.dir(workDir)
+	if err != nil {
+		return Result{}, fmt.Errorf("gemini: %w", err)
+	}
+
+	// Without -p, gemini runs non-interactively on piped stdin
+	args := []string{"--output-format", "json"}
+	if sb.Enabled() {
+		args = append(args, "--allowed-tools", strings.Join([]string{"read_file", "read_many_files", "glob", "search_file_content", "list_directory"}, ","))
+	}
 
 	if opts.SessionID != "" {
 		args = append(args, "--resume", opts.SessionID)
@@ -40,12 +54,15 @@ func (g *Gemini) Query(ctx context.Context, prompt string, workDir string, opts
 		args = append(args, "--model", opts.Model)
 	}
 
-	cmd := exec.CommandContext(ctx, "gemini", args...)
-	cmd.Dir = workDir
+	cmd := exec.CommandContext(ctx, cfg.binary("gemini"), args...)
+	cmd.Dir = sb.Dir(workDir)
+	cmd.Env = sb.Environ("gemini", "GOOGLE_")
+	cmd.Stdin = strings.NewReader(prompt)
+	cfg.apply(cmd)
 
 	out, err := cmd.CombinedOutput()
 	if err != nil {
-		return Result{}, fmt.Errorf("gemini: %w\n%s", err, string(out))
+		return Result{}, cliError(ctx, "gemini", err, out)
 	}
 
 	// Try to parse JSON response
@@ -0,0 +1,70 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Health is the result of a successful health check
+type Health struct {
+	Version string        // CLI or server version, if known
+	Detail  string        // Extra context, e.g. "12 recordings"
+	Offline bool          // No model behind it; skip the round trip
+	Latency time.Duration // Round trip time, set by CheckHealth
+}
+
+// HealthChecker is implemented by backends with cheap checks (version,
+// reachability, auth) that don't need a model call
+type HealthChecker interface {
+	Health(ctx context.Context) (Health, error)
+}
+
+// healthPrompt is the trivial round trip CheckHealth sends
+const healthPrompt = "Reply with exactly this and nothing else: SELECT 1"
+
+// CheckHealth runs b's own checks, then a trivial query to prove the
+// backend is installed, logged in and answering. Errors are classified
+// like query errors so callers can suggest a fix.
+func CheckHealth(ctx context.Context, b Backend, workDir string, opts Options) (Health, error) {
+	if !b.Available() {
+		return Health{}, &Error{
+			Backend: b.Name(),
+			Kind:    KindNotInstalled,
+			Err:     fmt.Errorf("%s not installed", b.Name()),
+		}
+	}
+
+	var h Health
+	if hc, ok := b.(HealthChecker); ok {
+		var err error
+		if h, err = hc.Health(ctx); err != nil {
+			return h, err
+		}
+	}
+	if h.Offline {
+		return h, nil
+	}
+
+	start := time.Now()
+	result, err := b.Query(ctx, healthPrompt, workDir, opts)
+	h.Latency = time.Since(start)
+	if err != nil {
+		return h, err
+	}
+	if strings.TrimSpace(result.Response) == "" {
+		return h, malformedError(b.Name(), fmt.Errorf("%s: empty response", b.Name()))
+	}
+	return h, nil
+}
+
+// cliVersion runs `<bin> --version` and returns the first line
+func cliVersion(ctx context.Context, name, bin string) (Health, error) {
+	out, err := exec.CommandContext(ctx, bin, "--version").CombinedOutput()
+	if err != nil {
+		return Health{}, cliError(ctx, name, err, out)
+	}
+	return Health{Version: firstLine(strings.TrimSpace(string(out)))}, nil
+}
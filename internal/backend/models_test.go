@@ -0,0 +1,41 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestBuiltinModels(t *testing.T) {
+	tests := []struct {
+		backend ModelLister
+		model   string
+		ok      bool
+	}{
+		{&Codex{}, "gpt-4o-mini", true}, // qry's default for codex
+		{&Codex{}, "gpt-4o-mni", false},
+		{&Cursor{}, "auto", true}, // qry's default for cursor
+		{&Cursor{}, "atuo", false},
+		{&Claude{}, "claude-sonnet-4-5", true},
+	}
+	for _, tt := range tests {
+		models, err := tt.backend.Models(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := MatchModel(models, tt.model); got != tt.ok {
+			t.Errorf("%T: MatchModel(%q) = %v, want %v", tt.backend, tt.model, got, tt.ok)
+		}
+	}
+}
+
+func TestConfiguredModelsReplaceBuiltin(t *testing.T) {
+	viper.Set("backends.codex.models", []string{"gpt-6*"})
+	defer viper.Set("backends.codex.models", nil)
+
+	models, _ := (&Codex{}).Models(context.Background())
+	if !MatchModel(models, "gpt-6-codex") || MatchModel(models, "gpt-4o-mini") {
+		t.Errorf("models = %q, want only the configured list", models)
+	}
+}
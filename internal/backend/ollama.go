@@ -0,0 +1,253 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/amansingh-afk/qry/internal/schema"
+	"github.com/spf13/viper"
+)
+
+// Ollama calls a local Ollama-style HTTP API. Nothing leaves the machine,
+// which makes it usable in air-gapped environments.
+//
+// A raw model can't explore the repo like the agent CLIs do, so every
+// query is sent together with schema files collected from the work dir.
+//
+//	backends:
+//	  ollama:
+//	    host: http://localhost:11434
+//	    context_bytes: 65536
+type Ollama struct {
+	Host       string
+	HTTPClient *http.Client
+}
+
+func (o *Ollama) Name() string { return "ollama" }
+
+func (o *Ollama) InstallCmd() string {
+	return "curl -fsSL https://ollama.com/install.sh | sh"
+}
+
+// Available checks that the Ollama server answers
+func (o *Ollama) Available() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.host()+"/api/version", nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := o.client().Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode == http.StatusOK
+}
+
+func (o *Ollama) host() string {
+	host := o.Host
+	if host == "" {
+		host = viper.GetString("backends.ollama.host")
+	}
+	if host == "" {
+		host = os.Getenv("OLLAMA_HOST")
+	}
+	if host == "" {
+		host = "http://localhost:11434"
+	}
+	// OLLAMA_HOST is commonly set without a scheme (127.0.0.1:11434)
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	return strings.TrimSuffix(host, "/")
+}
+
+func (o *Ollama) client() *http.Client {
+	if o.HTTPClient != nil {
+		return o.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// Health reads the server version from /api/version
+func (o *Ollama) Health(ctx context.Context) (Health, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.host()+"/api/version", nil)
+	if err != nil {
+		return Health{}, fmt.Errorf("ollama: %w", err)
+	}
+
+	resp, err := o.client().Do(req)
+	if err != nil {
+		return Health{}, transportError(ctx, "ollama", err)
+	}
+	defer resp.Body.Close()
+
+	out, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Health{}, transportError(ctx, "ollama", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Health{}, httpError("ollama", resp.StatusCode, resp.Status, out)
+	}
+
+	var parsed struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		return Health{}, malformedError("ollama", fmt.Errorf("ollama: invalid version response: %w", err))
+	}
+	return Health{Version: parsed.Version, Detail: o.host()}, nil
+}
+
+// Models lists locally pulled models from /api/tags. Models tagged
+// ":latest" are also listed without the tag, as Ollama accepts both.
+func (o *Ollama) Models(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.host()+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("ollama: %w", err)
+	}
+
+	resp, err := o.client().Do(req)
+	if err != nil {
+		return nil, transportError(ctx, "ollama", err)
+	}
+	defer resp.Body.Close()
+
+	out, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, transportError(ctx, "ollama", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpError("ollama", resp.StatusCode, resp.Status, out)
+	}
+
+	var parsed struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		return nil, malformedError("ollama", fmt.Errorf("ollama: invalid tags response: %w", err))
+	}
+
+	var models []string
+	for _, m := range parsed.Models {
+		models = append(models, m.Name)
+		if name, ok := strings.CutSuffix(m.Name, ":latest"); ok {
+			models = append(models, name)
+		}
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+}
+
+// ollamaResponse represents the JSON body returned by /api/chat
+type ollamaResponse struct {
+	Message         ollamaMessage `json:"message"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error,omitempty"`
+}
+
+// Query sends schema context as a system message followed by the prompt.
+// The API is stateless, so no session ID is returned.
+func (o *Ollama) Query(ctx context.Context, prompt string, workDir string, opts Options) (Result, error) {
+	var messages []ollamaMessage
+
+	// Send only the declared schema sources when there are any
+	dir, err := ConfigFor("ollama").dir(workDir)
+	if err != nil {
+		return Result{}, fmt.Errorf("ollama: %w", err)
+	}
+	budget := viper.GetInt("backends.ollama.context_bytes")
+	var files []schema.File
+	if paths := SchemaPaths(); len(paths) > 0 {
+		files, err = schema.CollectPaths(workDir, paths, budget)
+	} else {
+		files, err = schema.Collect(dir, budget)
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("ollama: collecting schema: %w", err)
+	}
+	if schemaCtx := schema.Context(files); schemaCtx != "" {
+		messages = append(messages, ollamaMessage{Role: "system", Content: schemaCtx})
+	}
+	messages = append(messages, ollamaMessage{Role: "user", Content: prompt})
+
+	body, err := json.Marshal(ollamaRequest{
+		Model:    opts.Model,
+		Messages: messages,
+		Stream:   false,
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("ollama: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.host()+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return Result{}, fmt.Errorf("ollama: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client().Do(req)
+	if err != nil {
+		return Result{}, transportError(ctx, "ollama", err)
+	}
+	defer resp.Body.Close()
+
+	out, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Result{}, transportError(ctx, "ollama", err)
+	}
+
+	var parsed ollamaResponse
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return Result{}, httpError("ollama", resp.StatusCode, resp.Status, out)
+		}
+		return Result{}, malformedError("ollama", fmt.Errorf("ollama: invalid response: %w\n%s", err, string(out)))
+	}
+
+	if parsed.Error != "" {
+		if resp.StatusCode != http.StatusOK {
+			return Result{}, httpError("ollama", resp.StatusCode, resp.Status, []byte(parsed.Error))
+		}
+		return Result{}, cliError(ctx, "ollama", fmt.Errorf("%s", parsed.Error), nil)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, httpError("ollama", resp.StatusCode, resp.Status, out)
+	}
+
+	return Result{
+		Response: strings.TrimSpace(parsed.Message.Content),
+		Usage: Usage{
+			InputTokens:  parsed.PromptEvalCount,
+			OutputTokens: parsed.EvalCount,
+			Turns:        1,
+		},
+	}, nil
+}
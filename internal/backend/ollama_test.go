@@ -0,0 +1,134 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOllamaQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   Result
+		kind   ErrorKind // Empty when the query succeeds
+	}{
+		{
+			name:   "success",
+			status: http.StatusOK,
+			body:   `{"message": {"role": "assistant", "content": "\nSELECT 1\n"}, "prompt_eval_count": 300, "eval_count": 12, "done": true}`,
+			want:   Result{Response: "SELECT 1", Usage: Usage{InputTokens: 300, OutputTokens: 12, Turns: 1}},
+		},
+		{name: "unauthorized proxy", status: http.StatusUnauthorized, body: `{"error": "unauthorized"}`, kind: KindAuth},
+		{name: "busy", status: http.StatusServiceUnavailable, body: `server busy`, kind: KindRateLimit},
+		{name: "model missing", status: http.StatusNotFound, body: `{"error": "model \"nope\" not found, try pulling it first"}`, kind: KindUnknown},
+		{name: "error with 200", status: http.StatusOK, body: `{"error": "context length exceeded"}`, kind: KindUnknown},
+		{name: "not json", status: http.StatusOK, body: `<html>`, kind: KindMalformed},
+	}
+
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "schema.sql"), []byte("CREATE TABLE users (id int);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got ollamaRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/api/chat" {
+					t.Errorf("request = %s %s", r.Method, r.URL.Path)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("decoding request: %v", err)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			o := &Ollama{Host: srv.URL, HTTPClient: srv.Client()}
+			res, err := o.Query(context.Background(), "list users", workDir, Options{Model: "llama-test"})
+
+			// The schema goes first as a system message
+			if got.Model != "llama-test" || got.Stream || len(got.Messages) != 2 {
+				t.Fatalf("request body = %+v", got)
+			}
+			if m := got.Messages[0]; m.Role != "system" || !strings.Contains(m.Content, "CREATE TABLE users") {
+				t.Errorf("system message = %+v, want the schema", m)
+			}
+			if m := got.Messages[1]; m.Role != "user" || m.Content != "list users" {
+				t.Errorf("user message = %+v", m)
+			}
+
+			if tt.kind != "" {
+				if err == nil {
+					t.Fatalf("Query succeeded, want a %s error", tt.kind)
+				}
+				if kind := KindOf(err); kind != tt.kind {
+					t.Errorf("kind = %s, want %s (%v)", kind, tt.kind, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Query: %v", err)
+			}
+			if res != tt.want {
+				t.Errorf("Result = %+v, want %+v", res, tt.want)
+			}
+		})
+	}
+}
+
+func TestOllamaModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"models": [{"name": "qwen2.5-coder:7b"}, {"name": "llama3:latest"}]}`))
+	}))
+	defer srv.Close()
+
+	// OLLAMA_HOST style hosts have no scheme
+	o := &Ollama{Host: strings.TrimPrefix(srv.URL, "http://"), HTTPClient: srv.Client()}
+	models, err := o.Models(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"llama3", "llama3:latest", "qwen2.5-coder:7b"}; !slices.Equal(models, want) {
+		t.Errorf("models = %q, want %q", models, want)
+	}
+}
+
+func TestOllamaModelsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		kind   ErrorKind
+	}{
+		{"server error", http.StatusServiceUnavailable, "busy", KindRateLimit},
+		{"not json", http.StatusOK, "<html>", KindMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			o := &Ollama{Host: srv.URL, HTTPClient: srv.Client()}
+			if _, err := o.Models(context.Background()); KindOf(err) != tt.kind {
+				t.Errorf("err = %v, want a %s error", err, tt.kind)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// OpenAI talks to any OpenAI-compatible /v1/chat/completions endpoint
+// (OpenAI itself, vLLM, LiteLLM, local inference gateways).
+//
+// Settings come from .qry.yaml:
+//
+//	backends:
+//	  openai:
+//	    base_url: http://localhost:8080/v1
+//	    api_key_env: OPENAI_API_KEY
+//
+// The fields below override config and exist mainly for tests.
+type OpenAI struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+func (o *OpenAI) Name() string { return "openai" }
+
+func (o *OpenAI) InstallCmd() string {
+	return "set backends.openai.base_url in .qry.yaml"
+}
+
+func (o *OpenAI) Available() bool {
+	if o.baseURL() == "" {
+		return false
+	}
+	// A key env var was explicitly configured but is empty
+	if viper.IsSet("backends.openai.api_key_env") && o.apiKey() == "" {
+		return false
+	}
+	return true
+}
+
+func (o *OpenAI) baseURL() string {
+	url := o.BaseURL
+	if url == "" {
+		url = viper.GetString("backends.openai.base_url")
+	}
+	return strings.TrimSuffix(url, "/")
+}
+
+func (o *OpenAI) apiKey() string {
+	if o.APIKey != "" {
+		return o.APIKey
+	}
+	env := viper.GetString("backends.openai.api_key_env")
+	if env == "" {
+		env = "OPENAI_API_KEY"
+	}
+	return os.Getenv(env)
+}
+
+func (o *OpenAI) client() *http.Client {
+	if o.HTTPClient != nil {
+		return o.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIRequest struct {
+	Model    string          `json:"model,omitempty"`
+	Messages []openAIMessage `json:"messages"`
+}
+
+// openAIResponse represents the JSON body returned by /chat/completions
+type openAIResponse struct {
+	ID      string `json:"id"`
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Models lists the endpoint's models from GET /models
+func (o *OpenAI) Models(ctx context.Context) ([]string, error) {
+	base := o.baseURL()
+	if base == "" {
+		return nil, fmt.Errorf("openai: base_url not configured")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("openai: %w", err)
+	}
+	if key := o.apiKey(); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	resp, err := o.client().Do(req)
+	if err != nil {
+		return nil, transportError(ctx, "openai", err)
+	}
+	defer resp.Body.Close()
+
+	out, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, transportError(ctx, "openai", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpError("openai", resp.StatusCode, resp.Status, out)
+	}
+
+	var parsed struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		return nil, malformedError("openai", fmt.Errorf("openai: invalid models response: %w", err))
+	}
+
+	models := make([]string, 0, len(parsed.Data))
+	for _, m := range parsed.Data {
+		models = append(models, m.ID)
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+// Health lists models, which checks the endpoint and the API key without
+// spending tokens
+func (o *OpenAI) Health(ctx context.Context) (Health, error) {
+	models, err := o.Models(ctx)
+	if err != nil {
+		return Health{}, err
+	}
+	return Health{Detail: fmt.Sprintf("%d models at %s", len(models), o.baseURL())}, nil
+}
+
+// Query sends the prompt as a single user message. The API is stateless,
+// so no session ID is returned and every query carries the full prompt.
+func (o *OpenAI) Query(ctx context.Context, prompt string, workDir string, opts Options) (Result, error) {
+	base := o.baseURL()
+	if base == "" {
+		return Result{}, fmt.Errorf("openai: base_url not configured")
+	}
+
+	body, err := json.Marshal(openAIRequest{
+		Model:    opts.Model,
+		Messages: []openAIMessage{{Role: "user", Content: prompt}},
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("openai: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return Result{}, fmt.Errorf("openai: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if key := o.apiKey(); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	resp, err := o.client().Do(req)
+	if err != nil {
+		return Result{}, transportError(ctx, "openai", err)
+	}
+	defer resp.Body.Close()
+
+	out, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Result{}, transportError(ctx, "openai", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, httpError("openai", resp.StatusCode, resp.Status, out)
+	}
+
+	var parsed openAIResponse
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		return Result{}, malformedError("openai", fmt.Errorf("openai: invalid response: %w\n%s", err, string(out)))
+	}
+
+	if parsed.Error != nil {
+		return Result{}, cliError(ctx, "openai", fmt.Errorf("%s", parsed.Error.Message), nil)
+	}
+
+	if len(parsed.Choices) == 0 {
+		return Result{}, malformedError("openai", fmt.Errorf("openai: response has no choices\n%s", string(out)))
+	}
+
+	return Result{
+		Response: strings.TrimSpace(parsed.Choices[0].Message.Content),
+		Usage: Usage{
+			InputTokens:  parsed.Usage.PromptTokens,
+			OutputTokens: parsed.Usage.CompletionTokens,
+			Turns:        1,
+		},
+	}, nil
+}
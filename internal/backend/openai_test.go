@@ -0,0 +1,94 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAIQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   Result
+		kind   ErrorKind // Empty when the query succeeds
+	}{
+		{
+			name:   "success",
+			status: http.StatusOK,
+			body: `{"id": "c1", "choices": [{"message": {"role": "assistant", "content": "  SELECT 1  "}}],
+				"usage": {"prompt_tokens": 120, "completion_tokens": 8}}`,
+			want: Result{Response: "SELECT 1", Usage: Usage{InputTokens: 120, OutputTokens: 8, Turns: 1}},
+		},
+		{name: "unauthorized", status: http.StatusUnauthorized, body: `{"error": {"message": "bad key"}}`, kind: KindAuth},
+		{name: "rate limited", status: http.StatusTooManyRequests, body: `{"error": {"message": "slow down"}}`, kind: KindRateLimit},
+		{name: "no choices", status: http.StatusOK, body: `{"id": "c1", "choices": []}`, kind: KindMalformed},
+		{name: "not json", status: http.StatusOK, body: `<html>`, kind: KindMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got openAIRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+					t.Errorf("request = %s %s", r.Method, r.URL.Path)
+				}
+				if auth := r.Header.Get("Authorization"); auth != "Bearer sk-test" {
+					t.Errorf("Authorization = %q", auth)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("decoding request: %v", err)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			o := &OpenAI{BaseURL: srv.URL + "/v1/", APIKey: "sk-test", HTTPClient: srv.Client()}
+			res, err := o.Query(context.Background(), "list users", "", Options{Model: "gpt-test"})
+
+			if got.Model != "gpt-test" || len(got.Messages) != 1 || got.Messages[0].Content != "list users" {
+				t.Errorf("request body = %+v", got)
+			}
+
+			if tt.kind != "" {
+				if err == nil {
+					t.Fatalf("Query succeeded, want a %s error", tt.kind)
+				}
+				if kind := KindOf(err); kind != tt.kind {
+					t.Errorf("kind = %s, want %s (%v)", kind, tt.kind, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Query: %v", err)
+			}
+			if res != tt.want {
+				t.Errorf("Result = %+v, want %+v", res, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAIModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": [{"id": "gpt-b"}, {"id": "gpt-a"}]}`))
+	}))
+	defer srv.Close()
+
+	o := &OpenAI{BaseURL: srv.URL, HTTPClient: srv.Client()}
+	models, err := o.Models(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 2 || models[0] != "gpt-a" || models[1] != "gpt-b" {
+		t.Errorf("models = %q, want sorted ids", models)
+	}
+}
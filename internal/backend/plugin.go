@@ -0,0 +1,351 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PluginProtocolVersion is the plugin protocol qry speaks. Plugins report
+// theirs from "capabilities"; a different major version is rejected.
+const PluginProtocolVersion = 1
+
+// PluginPrefix is the executable name prefix for plugin backends
+const PluginPrefix = "qry-backend-"
+
+// PluginCapabilities is the result of the "capabilities" method
+type PluginCapabilities struct {
+	ProtocolVersion int    `json:"protocol_version"`
+	Sessions        bool   `json:"sessions,omitempty"` // Returns session IDs for follow-ups
+	Models          bool   `json:"models,omitempty"`   // Implements "list_models"
+	Health          bool   `json:"health,omitempty"`   // Implements "health"
+	Install         string `json:"install,omitempty"`  // Setup hint, e.g. how to log in
+}
+
+// PluginQuery is the params of the "query" method
+type PluginQuery struct {
+	Prompt      string   `json:"prompt"`
+	WorkDir     string   `json:"work_dir"`
+	Model       string   `json:"model,omitempty"`
+	Dialect     string   `json:"dialect,omitempty"`
+	SessionID   string   `json:"session_id,omitempty"`
+	Sandbox     Sandbox  `json:"sandbox"`                // The plugin is expected to enforce it
+	SchemaPaths []string `json:"schema_paths,omitempty"` // Relative to work_dir
+}
+
+// PluginQueryResult is the result of the "query" method
+type PluginQueryResult struct {
+	Response  string `json:"response"`
+	SessionID string `json:"session_id,omitempty"`
+	Usage     Usage  `json:"usage"`
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int    `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int            `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError may carry an ErrorKind in data.kind so fallback and retries
+// treat plugin failures like built-in ones
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		Kind ErrorKind `json:"kind"`
+	} `json:"data"`
+}
+
+// Plugin is an out-of-tree backend: a `qry-backend-<name>` executable
+// on PATH speaking JSON-RPC 2.0 over stdio. Each call starts the plugin,
+// writes one request line to stdin and reads one response line from
+// stdout. Stderr is only used in error messages. See docs/PLUGINS.md.
+type Plugin struct {
+	name string
+	path string
+
+	mu   sync.Mutex
+	caps *PluginCapabilities // Set once the plugin has answered
+}
+
+// NewPlugin creates a backend for the plugin executable at path
+func NewPlugin(name, path string) *Plugin {
+	return &Plugin{name: name, path: path}
+}
+
+func (p *Plugin) Name() string { return p.name }
+
+func (p *Plugin) InstallCmd() string {
+	if caps, err := p.capabilities(); err == nil && caps.Install != "" {
+		return caps.Install
+	}
+	return "put " + PluginPrefix + p.name + " on PATH"
+}
+
+func (p *Plugin) Available() bool {
+	_, err := os.Stat(p.path)
+	return err == nil
+}
+
+func (p *Plugin) Query(ctx context.Context, prompt string, workDir string, opts Options) (Result, error) {
+	caps, err := p.capabilities()
+	if err != nil {
+		return Result{}, err
+	}
+
+	// Don't send a session ID the plugin doesn't understand
+	sessionID := opts.SessionID
+	if !caps.Sessions {
+		sessionID = ""
+	}
+
+	sb := SandboxFor(p.name)
+	if err := sb.Check(); err != nil {
+		return Result{}, fmt.Errorf("%s: %w", p.name, err)
+	}
+	dir, err := ConfigFor(p.name).dir(workDir)
+	if err != nil {
+		return Result{}, fmt.Errorf("%s: %w", p.name, err)
+	}
+	workDir = sb.Dir(dir)
+
+	var res PluginQueryResult
+	err = p.call(ctx, workDir, "query", PluginQuery{
+		Prompt:      prompt,
+		WorkDir:     workDir,
+		Model:       opts.Model,
+		Dialect:     opts.Dialect,
+		SessionID:   sessionID,
+		Sandbox:     sb,
+		SchemaPaths: SchemaPaths(),
+	}, &res)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Response:  strings.TrimSpace(res.Response),
+		SessionID: res.SessionID,
+		Usage:     res.Usage,
+	}, nil
+}
+
+// Models calls "list_models". Returns nil if the plugin doesn't support it.
+func (p *Plugin) Models(ctx context.Context) ([]string, error) {
+	caps, err := p.capabilities()
+	if err != nil || !caps.Models {
+		return nil, err
+	}
+
+	var res struct {
+		Models []string `json:"models"`
+	}
+	if err := p.call(ctx, "", "list_models", nil, &res); err != nil {
+		return nil, err
+	}
+	return res.Models, nil
+}
+
+// Health calls "health". Plugins without it are healthy if they answer
+// "capabilities" with a compatible protocol version.
+func (p *Plugin) Health(ctx context.Context) (Health, error) {
+	caps, err := p.capabilities()
+	if err != nil || !caps.Health {
+		return Health{}, err
+	}
+
+	var res struct {
+		OK      bool   `json:"ok"`
+		Message string `json:"message"`
+		Version string `json:"version"`
+	}
+	if err := p.call(ctx, "", "health", nil, &res); err != nil {
+		return Health{}, err
+	}
+	if !res.OK {
+		err := fmt.Errorf("%s: %s", p.name, res.Message)
+		return Health{}, &Error{Backend: p.name, Kind: classify(ctx, err, ""), Err: err}
+	}
+	return Health{Version: res.Version, Detail: res.Message}, nil
+}
+
+// capabilities asks the plugin and caches a good answer. Errors aren't
+// cached, so a plugin that timed out or was upgraded is asked again.
+func (p *Plugin) capabilities() (PluginCapabilities, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.caps != nil {
+		return *p.caps, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var caps PluginCapabilities
+	if err := p.call(ctx, "", "capabilities", map[string]int{
+		"protocol_version": PluginProtocolVersion,
+	}, &caps); err != nil {
+		return PluginCapabilities{}, err
+	}
+	if caps.ProtocolVersion != PluginProtocolVersion {
+		return PluginCapabilities{}, &Error{
+			Backend: p.name,
+			Kind:    KindNotInstalled,
+			Err: fmt.Errorf("%s: plugin speaks protocol version %d, qry needs %d",
+				p.name, caps.ProtocolVersion, PluginProtocolVersion),
+		}
+	}
+
+	p.caps = &caps
+	return caps, nil
+}
+
+// call runs one JSON-RPC request against a fresh plugin process
+func (p *Plugin) call(ctx context.Context, workDir, method string, params, result any) error {
+	req, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: 1, Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("%s: %w", p.name, err)
+	}
+
+	cmd := exec.CommandContext(ctx, p.path)
+	cmd.Dir = workDir
+	cmd.Env = SandboxFor(p.name).Environ(p.name)
+	ConfigFor(p.name).apply(cmd)
+	cmd.Stdin = bytes.NewReader(append(req, '\n'))
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+
+	resp, ok := findResponse(stdout.Bytes())
+	if !ok {
+		if runErr != nil {
+			return cliError(ctx, p.name, runErr, stderr.Bytes())
+		}
+		return malformedError(p.name, fmt.Errorf("%s: no JSON-RPC response to %q\n%s", p.name, method, stdout.String()))
+	}
+
+	if resp.Error != nil {
+		kind := resp.Error.Data.Kind
+		if kind == "" {
+			kind = classify(ctx, fmt.Errorf("%s", resp.Error.Message), stderr.String())
+		}
+		return &Error{
+			Backend: p.name,
+			Kind:    kind,
+			Err:     fmt.Errorf("%s: %s", p.name, resp.Error.Message),
+		}
+	}
+
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return malformedError(p.name, fmt.Errorf("%s: invalid %q result: %w\n%s", p.name, method, err, string(resp.Result)))
+	}
+	return nil
+}
+
+// findResponse returns the response to request 1. Other stdout lines
+// (logging, notifications) are ignored.
+func findResponse(out []byte) (rpcResponse, bool) {
+	for _, line := range strings.Split(string(out), "\n") {
+		var resp rpcResponse
+		if err := json.Unmarshal([]byte(strings.TrimSpace(line)), &resp); err != nil {
+			continue
+		}
+		if resp.ID != nil && *resp.ID == 1 && (resp.Result != nil || resp.Error != nil) {
+			return resp, true
+		}
+	}
+	return rpcResponse{}, false
+}
+
+// LoadPlugins registers every qry-backend-<name> executable on PATH.
+// Backends that are already registered (built-in or from config) win,
+// as does the first match on PATH.
+func LoadPlugins() {
+	found := make(map[string]string)
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name, ok := pluginName(e.Name(), runtime.GOOS == "windows")
+			if !ok || e.IsDir() {
+				continue
+			}
+			if _, ok := found[name]; ok {
+				continue
+			}
+
+			path := filepath.Join(dir, e.Name())
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			// Windows has no execute bit: the extension says it's runnable
+			if runtime.GOOS != "windows" && info.Mode()&0111 == 0 {
+				continue
+			}
+			found[name] = path
+		}
+	}
+
+	for name, path := range found {
+		if _, ok := registry[name]; ok {
+			continue
+		}
+		Register(name, NewPlugin(name, path))
+	}
+}
+
+// pluginName returns the backend name for a qry-backend-<name> file. On
+// Windows only executable extensions (PATHEXT, .exe by default) count,
+// and the extension isn't part of the name.
+func pluginName(file string, windows bool) (string, bool) {
+	if windows {
+		ext := filepath.Ext(file)
+		if ext == "" || !executableExt(ext) {
+			return "", false
+		}
+		file = strings.TrimSuffix(file, ext)
+	}
+
+	name := strings.TrimPrefix(file, PluginPrefix)
+	if name == file || name == "" {
+		return "", false
+	}
+	return name, true
+}
+
+// executableExt reports whether ext is in PATHEXT
+func executableExt(ext string) bool {
+	pathext := os.Getenv("PATHEXT")
+	if pathext == "" {
+		pathext = ".com;.exe;.bat;.cmd"
+	}
+	for _, e := range strings.Split(pathext, ";") {
+		if e != "" && strings.EqualFold(e, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestPluginCapabilitiesRetry(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake plugin is a shell script")
+	}
+
+	// Fails the first call, then answers
+	dir := t.TempDir()
+	path := filepath.Join(dir, PluginPrefix+"flaky")
+	script := `#!/bin/sh
+if [ ! -f "$0.ran" ]; then
+	touch "$0.ran"
+	echo "not ready" >&2
+	exit 1
+fi
+echo '{"jsonrpc":"2.0","id":1,"result":{"protocol_version":1,"install":"get flaky"}}'
+`
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPlugin("flaky", path)
+	if _, err := p.capabilities(); err == nil {
+		t.Fatal("first call succeeded, want the plugin's error")
+	}
+
+	caps, err := p.capabilities()
+	if err != nil {
+		t.Fatalf("second call: %v", err)
+	}
+	if caps.Install != "get flaky" {
+		t.Errorf("Install = %q", caps.Install)
+	}
+
+	// The good answer is cached: the plugin isn't run again
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.InstallCmd(); got != "get flaky" {
+		t.Errorf("cached InstallCmd = %q", got)
+	}
+}
+
+func TestPluginName(t *testing.T) {
+	t.Setenv("PATHEXT", ".COM;.EXE;.BAT;.CMD")
+
+	tests := []struct {
+		file    string
+		windows bool
+		want    string
+		ok      bool
+	}{
+		{"qry-backend-gateway", false, "gateway", true},
+		{"qry-backend-", false, "", false},
+		{"qry", false, "", false},
+		{"qry-backend-gateway.exe", true, "gateway", true},
+		{"qry-backend-gateway.EXE", true, "gateway", true},
+		{"qry-backend-gateway.cmd", true, "gateway", true},
+		{"qry-backend-gateway", true, "", false},
+		{"qry-backend-gateway.txt", true, "", false},
+		{"qry-backend-.exe", true, "", false},
+	}
+	for _, tt := range tests {
+		name, ok := pluginName(tt.file, tt.windows)
+		if name != tt.want || ok != tt.ok {
+			t.Errorf("pluginName(%q, %v) = %q, %v; want %q, %v", tt.file, tt.windows, name, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Prompts never go on the command line: argv is visible to every user
+// in `ps` and is capped by ARG_MAX. CLIs that read stdin get the prompt
+// there; the rest get a short instruction pointing at a temp file.
+
+// writePromptFile writes prompt to a private temp file under the repo's
+// .qry/ (inside the repo, so agents sandboxed to it can read it, and
+// gitignored by init). The caller must call cleanup once the CLI exits.
+func writePromptFile(workDir, prompt string) (path string, cleanup func(), err error) {
+	dir := filepath.Join(workDir, ".qry")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", nil, err
+	}
+
+	// CreateTemp uses mode 0600
+	f, err := os.CreateTemp(dir, "prompt-*.md")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup = func() { _ = os.Remove(f.Name()) }
+
+	if _, err := f.WriteString(prompt); err != nil {
+		f.Close()
+		cleanup()
+		return "", nil, err
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return f.Name(), cleanup, nil
+}
+
+// filePrompt is the argv stand-in for a prompt stored in a file
+func filePrompt(workDir, path string) string {
+	if rel, err := filepath.Rel(workDir, path); err == nil {
+		path = rel
+	}
+	return fmt.Sprintf("Read the file %s and follow the instructions in it. Do not mention the file.", path)
+}
@@ -0,0 +1,124 @@
+package backend
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeCLI records its argv, its stdin and any prompt file it was pointed
+// at into $QRY_FAKE_OUT, then prints a JSON reply
+const fakeCLI = `#!/bin/sh
+printf '%s\n' "$@" > "$QRY_FAKE_OUT/args"
+cat > "$QRY_FAKE_OUT/stdin"
+cat .qry/prompt-*.md > "$QRY_FAKE_OUT/file" 2>/dev/null
+echo '{"result": "SELECT 1", "session_id": "s1"}'
+`
+
+// installFakeCLI puts a fake CLI called name first on PATH and returns
+// the directory it records into
+func installFakeCLI(t *testing.T, name string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake CLIs are shell scripts")
+	}
+
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, name), []byte(fakeCLI), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	out := t.TempDir()
+	t.Setenv("QRY_FAKE_OUT", out)
+	return out
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestPromptNeverInArgv(t *testing.T) {
+	const prompt = "SELECT secret_marker FROM prompt_under_test"
+
+	tests := []struct {
+		bin     string
+		backend Backend
+		stdin   bool // Prompt on stdin, otherwise in a .qry/prompt-*.md file
+	}{
+		{"claude", &Claude{}, true},
+		{"codex", &Codex{}, true},
+		{"gemini", &Gemini{}, true},
+		{"cursor-agent", &Cursor{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.backend.Name(), func(t *testing.T) {
+			out := installFakeCLI(t, tt.bin)
+			workDir := t.TempDir()
+
+			res, err := tt.backend.Query(context.Background(), prompt, workDir, Options{})
+			if err != nil {
+				t.Fatalf("Query: %v", err)
+			}
+			if res.Response != "SELECT 1" {
+				t.Errorf("Response = %q, want %q", res.Response, "SELECT 1")
+			}
+
+			if args := readFile(t, filepath.Join(out, "args")); strings.Contains(args, "secret_marker") {
+				t.Errorf("prompt in argv:\n%s", args)
+			}
+
+			stdin := readFile(t, filepath.Join(out, "stdin"))
+			file := readFile(t, filepath.Join(out, "file"))
+			if tt.stdin {
+				if stdin != prompt {
+					t.Errorf("stdin = %q, want the prompt", stdin)
+				}
+			} else if file != prompt {
+				t.Errorf("prompt file = %q, want the prompt", file)
+			}
+
+			left, _ := filepath.Glob(filepath.Join(workDir, ".qry", "prompt-*"))
+			if len(left) > 0 {
+				t.Errorf("prompt files left behind: %v", left)
+			}
+		})
+	}
+}
+
+func TestWritePromptFile(t *testing.T) {
+	workDir := t.TempDir()
+
+	path, cleanup, err := writePromptFile(workDir, "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(path) != filepath.Join(workDir, ".qry") {
+		t.Errorf("path = %s, want it under .qry/", path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	if got := readFile(t, path); got != "SELECT 1" {
+		t.Errorf("contents = %q", got)
+	}
+
+	cleanup()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file still exists after cleanup")
+	}
+}
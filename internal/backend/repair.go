@@ -0,0 +1,34 @@
+package backend
+
+import (
+	"context"
+	"time"
+)
+
+// Repair returns a function that sends a follow-up asking b to fix its
+// reply to sent (see prompt.ResolveAnswer and prompt.ResolveTranslation).
+// It goes in the same session when result has one; otherwise it repeats
+// sent, since a stateless backend hasn't seen it. opts are the original
+// query's options, so the model and dialect carry over. The follow-up's
+// session and usage are folded into result.
+func Repair(ctx context.Context, b Backend, sent, workDir string, opts Options, timeout time.Duration, result *Result) func(string) (string, error) {
+	return func(repair string) (string, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		opts.SessionID = result.SessionID
+		if opts.SessionID == "" {
+			repair = sent + "\n\n" + repair
+		}
+
+		r, err := b.Query(ctx, repair, workDir, opts)
+		if err != nil {
+			return "", err
+		}
+		if r.SessionID != "" {
+			result.SessionID = r.SessionID
+		}
+		result.Usage = result.Usage.Add(r.Usage)
+		return r.Response, nil
+	}
+}
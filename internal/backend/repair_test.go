@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// recordingBackend answers every query with reply and remembers the last
+// prompt and options it got
+type recordingBackend struct {
+	reply  Result
+	prompt string
+	opts   Options
+}
+
+func (r *recordingBackend) Name() string       { return "recording" }
+func (r *recordingBackend) Available() bool    { return true }
+func (r *recordingBackend) InstallCmd() string { return "" }
+
+func (r *recordingBackend) Query(ctx context.Context, prompt string, workDir string, opts Options) (Result, error) {
+	r.prompt, r.opts = prompt, opts
+	return r.reply, nil
+}
+
+func TestRepair(t *testing.T) {
+	opts := Options{Model: "m1", Dialect: "mysql"}
+
+	t.Run("stateless", func(t *testing.T) {
+		b := &recordingBackend{reply: Result{Response: "fixed", Usage: Usage{InputTokens: 5}}}
+		result := Result{Usage: Usage{InputTokens: 10}}
+
+		got, err := Repair(context.Background(), b, "original", "", opts, time.Minute, &result)("fix it")
+		if err != nil || got != "fixed" {
+			t.Fatalf("Repair = %q, %v", got, err)
+		}
+		if b.prompt != "original\n\nfix it" {
+			t.Errorf("prompt = %q, want the original repeated", b.prompt)
+		}
+		if b.opts != opts {
+			t.Errorf("opts = %+v, want %+v", b.opts, opts)
+		}
+		if result.Usage.InputTokens != 15 {
+			t.Errorf("usage = %+v, want both calls", result.Usage)
+		}
+	})
+
+	t.Run("session", func(t *testing.T) {
+		b := &recordingBackend{reply: Result{Response: "fixed", SessionID: "s2"}}
+		result := Result{SessionID: "s1"}
+
+		if _, err := Repair(context.Background(), b, "original", "", opts, time.Minute, &result)("fix it"); err != nil {
+			t.Fatal(err)
+		}
+		if b.prompt != "fix it" {
+			t.Errorf("prompt = %q, want only the repair", b.prompt)
+		}
+		if want := (Options{Model: "m1", Dialect: "mysql", SessionID: "s1"}); b.opts != want {
+			t.Errorf("opts = %+v, want %+v", b.opts, want)
+		}
+		if result.SessionID != "s2" {
+			t.Errorf("SessionID = %q, want the repair's", result.SessionID)
+		}
+	})
+}
@@ -0,0 +1,269 @@
+package backend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultCassette is where recordings go unless backends.replay.cassette
+// says otherwise. Relative paths are resolved against the work dir.
+const DefaultCassette = ".qry/cassette.json"
+
+// Cassette holds recorded backend responses keyed by backend and
+// normalized prompt
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded prompt and the response it got
+type Interaction struct {
+	Key        string    `json:"key"`
+	Prompt     string    `json:"prompt"`
+	Backend    string    `json:"backend"`
+	Model      string    `json:"model,omitempty"`
+	Response   string    `json:"response"`
+	SessionID  string    `json:"session_id,omitempty"`
+	Usage      Usage     `json:"usage"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// PromptKey identifies a backend's prompt regardless of whitespace
+// differences. The backend is part of the key, so an ensemble recording
+// the same prompt on several backends keeps every response.
+func PromptKey(backend, prompt string) string {
+	sum := sha256.Sum256([]byte(backend + "\x00" + strings.Join(strings.Fields(prompt), " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+// cassetteMu serializes cassette writes (ensemble records concurrently)
+var cassetteMu sync.Mutex
+
+func cassettePath(workDir string) string {
+	path := viper.GetString("backends.replay.cassette")
+	if path == "" {
+		path = DefaultCassette
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workDir, path)
+	}
+	return path
+}
+
+// LoadCassette reads a cassette file. A missing file is an empty cassette.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Cassette{}, nil
+		}
+		return nil, err
+	}
+
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Find returns backend's recorded interaction for a prompt. An empty
+// backend matches the first recording of the prompt from any backend.
+func (c *Cassette) Find(backend, prompt string) (Interaction, bool) {
+	for _, in := range c.Interactions {
+		if backend != "" && in.Backend != backend {
+			continue
+		}
+		if in.Key == PromptKey(in.Backend, prompt) {
+			return in, true
+		}
+	}
+	return Interaction{}, false
+}
+
+// Put adds an interaction, replacing any earlier one for the same
+// backend and prompt
+func (c *Cassette) Put(in Interaction) {
+	for i := range c.Interactions {
+		if c.Interactions[i].Key == in.Key {
+			c.Interactions[i] = in
+			return
+		}
+	}
+	c.Interactions = append(c.Interactions, in)
+}
+
+// Save writes the cassette to disk
+func (c *Cassette) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// Replay serves responses from a cassette instead of calling a model.
+// Useful for demos and for exercising the query pipeline offline.
+//
+//	backends:
+//	  replay:
+//	    cassette: testdata/cassette.json
+//	    backend: claude   # only replay claude's recordings
+type Replay struct{}
+
+func (r *Replay) Name() string { return "replay" }
+
+func (r *Replay) InstallCmd() string {
+	return "record a cassette with: qry q --record \"<question>\""
+}
+
+// Available checks that a cassette exists for the current directory
+func (r *Replay) Available() bool {
+	wd, err := os.Getwd()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(cassettePath(wd))
+	return err == nil
+}
+
+// Health checks the cassette parses. There's no model to round-trip.
+func (r *Replay) Health(ctx context.Context) (Health, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return Health{}, err
+	}
+	path := cassettePath(wd)
+	c, err := LoadCassette(path)
+	if err != nil {
+		return Health{}, malformedError("replay", fmt.Errorf("replay: %w", err))
+	}
+	return Health{
+		Detail:  fmt.Sprintf("%d recordings in %s", len(c.Interactions), path),
+		Offline: true,
+	}, nil
+}
+
+// Query looks up the prompt in the cassette. The recorded session ID is
+// returned too, so follow-ups replay the same way they were recorded.
+func (r *Replay) Query(ctx context.Context, prompt string, workDir string, opts Options) (Result, error) {
+	path := cassettePath(workDir)
+
+	c, err := LoadCassette(path)
+	if err != nil {
+		return Result{}, fmt.Errorf("replay: %w", err)
+	}
+
+	from := viper.GetString("backends.replay.backend")
+	in, ok := c.Find(from, prompt)
+	if !ok {
+		err := fmt.Errorf("replay: no recording for this prompt in %s", path)
+		if from != "" {
+			err = fmt.Errorf("replay: no %s recording for this prompt in %s (key %s)", from, path, PromptKey(from, prompt)[:12])
+		}
+		return Result{}, &Error{Backend: "replay", Kind: KindUnknown, Err: err}
+	}
+
+	return Result{
+		Response:  in.Response,
+		SessionID: in.SessionID,
+		Usage:     in.Usage,
+	}, nil
+}
+
+// Recorder wraps a backend and saves every successful response to the
+// cassette. It keeps the wrapped backend's name so sessions and output
+// look the same as without recording.
+type Recorder struct {
+	Backend
+}
+
+// Record wraps b so its responses are written to the cassette
+func Record(b Backend) Backend {
+	if _, ok := b.(*Replay); ok {
+		return b
+	}
+	return &Recorder{Backend: b}
+}
+
+func (r *Recorder) Query(ctx context.Context, prompt string, workDir string, opts Options) (Result, error) {
+	result, err := r.Backend.Query(ctx, prompt, workDir, opts)
+	if err != nil {
+		return result, err
+	}
+	return result, r.save(prompt, workDir, opts, result)
+}
+
+// Models forwards to the wrapped backend
+func (r *Recorder) Models(ctx context.Context) ([]string, error) {
+	return ListModels(ctx, r.Backend)
+}
+
+// Health forwards to the wrapped backend
+func (r *Recorder) Health(ctx context.Context) (Health, error) {
+	if hc, ok := r.Backend.(HealthChecker); ok {
+		return hc.Health(ctx)
+	}
+	return Health{}, nil
+}
+
+// QueryStream forwards events and records the final result
+func (r *Recorder) QueryStream(ctx context.Context, prompt string, workDir string, opts Options) (<-chan Event, error) {
+	in := Stream(ctx, r.Backend, prompt, workDir, opts)
+	out := make(chan Event)
+
+	go func() {
+		defer close(out)
+		for ev := range in {
+			if ev.Type == EventDone {
+				if err := r.save(prompt, workDir, opts, ev.Result); err != nil {
+					ev = Event{Type: EventError, Err: err}
+				}
+			}
+			out <- ev
+		}
+	}()
+
+	return out, nil
+}
+
+func (r *Recorder) save(prompt, workDir string, opts Options, result Result) error {
+	cassetteMu.Lock()
+	defer cassetteMu.Unlock()
+
+	path := cassettePath(workDir)
+	c, err := LoadCassette(path)
+	if err != nil {
+		return fmt.Errorf("record: %w", err)
+	}
+
+	c.Put(Interaction{
+		Key:        PromptKey(r.Name(), prompt),
+		Prompt:     prompt,
+		Backend:    r.Name(),
+		Model:      opts.Model,
+		Response:   result.Response,
+		SessionID:  result.SessionID,
+		Usage:      result.Usage,
+		RecordedAt: time.Now(),
+	})
+
+	if err := c.Save(path); err != nil {
+		return fmt.Errorf("record: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package backend
+
+import "testing"
+
+func TestCassetteKeysByBackend(t *testing.T) {
+	const prompt = "list  active\nusers"
+
+	var c Cassette
+	c.Put(Interaction{Key: PromptKey("claude", prompt), Backend: "claude", Response: "from claude"})
+	c.Put(Interaction{Key: PromptKey("codex", prompt), Backend: "codex", Response: "from codex"})
+	c.Put(Interaction{Key: PromptKey("codex", prompt), Backend: "codex", Response: "from codex, again"})
+
+	if len(c.Interactions) != 2 {
+		t.Fatalf("%d interactions, want one per backend", len(c.Interactions))
+	}
+
+	tests := []struct {
+		backend, want string
+	}{
+		{"", "from claude"},
+		{"claude", "from claude"},
+		{"codex", "from codex, again"},
+	}
+	for _, tt := range tests {
+		in, ok := c.Find(tt.backend, "list active users")
+		if !ok || in.Response != tt.want {
+			t.Errorf("Find(%q) = %q, %v; want %q", tt.backend, in.Response, ok, tt.want)
+		}
+	}
+
+	if _, ok := c.Find("gemini", prompt); ok {
+		t.Error("Find(gemini) matched another backend's recording")
+	}
+}
@@ -0,0 +1,165 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Sandbox limits what an agent CLI can do while generating SQL. The
+// top-level `sandbox:` applies to every backend; `backends.<name>.sandbox`
+// overrides individual fields.
+//
+//	sandbox:
+//	  mode: read-only              # or "off" to use the CLI's own config
+//	  allowed_tools: [Read, Grep, Glob, LS]
+//	  dirs: [db, app/models]       # agent starts in the first, may read the rest
+//	  env: [DATABASE_URL]          # extra env vars to pass through
+type Sandbox struct {
+	Mode         string   `mapstructure:"mode" json:"mode"`
+	AllowedTools []string `mapstructure:"allowed_tools" json:"allowed_tools,omitempty"`
+	Dirs         []string `mapstructure:"dirs" json:"dirs,omitempty"`
+	Env          []string `mapstructure:"env" json:"env,omitempty"`
+}
+
+const (
+	SandboxReadOnly = "read-only"
+	SandboxOff      = "off"
+)
+
+// Tools that only read. Used by CLIs that take an allowlist.
+var readOnlyTools = []string{"Read", "Grep", "Glob", "LS"}
+
+// Tools that write, execute or reach the network. Explicitly denied so a
+// permissive global agent config can't re-enable them.
+var writeTools = []string{
+	"Bash", "Edit", "MultiEdit", "Write", "NotebookEdit",
+	"WebFetch", "WebSearch", "Task", "KillShell", "BashOutput",
+}
+
+// Env vars every CLI needs to start and find its credentials
+var baseEnv = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "TERM", "TZ", "LANG",
+	"TMPDIR", "TMP", "TEMP",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy",
+	"SSL_CERT_FILE", "SSL_CERT_DIR", "NODE_EXTRA_CA_CERTS",
+	"SYSTEMROOT", "APPDATA", "LOCALAPPDATA", "USERPROFILE", // Windows
+}
+
+var baseEnvPrefixes = []string{"LC_", "XDG_", "QRY_"}
+
+// SandboxFor returns the effective sandbox for a backend
+func SandboxFor(name string) Sandbox {
+	sb := Sandbox{Mode: SandboxReadOnly}
+	for _, key := range []string{"sandbox", "backends." + name + ".sandbox"} {
+		// `sandbox: off` is shorthand for `sandbox: {mode: off}`
+		if mode, ok := viper.Get(key).(string); ok {
+			sb.Mode = mode
+			continue
+		}
+		_ = viper.UnmarshalKey(key, &sb)
+	}
+
+	// Anything but an explicit "off" stays read-only
+	if sb.Mode != SandboxOff {
+		sb.Mode = SandboxReadOnly
+	}
+	if len(sb.AllowedTools) == 0 {
+		sb.AllowedTools = readOnlyTools
+	}
+	return sb
+}
+
+// Enabled reports whether the sandbox applies
+func (s Sandbox) Enabled() bool {
+	return s.Mode != SandboxOff
+}
+
+// Check rejects `dirs` that leave the repo: absolute paths and ".."
+func (s Sandbox) Check() error {
+	for _, d := range s.Dirs {
+		if !filepath.IsLocal(d) {
+			return fmt.Errorf("sandbox.dirs: %q is outside the repository", d)
+		}
+	}
+	return nil
+}
+
+// Dir is where the agent runs: the first of `dirs`, or the work dir
+func (s Sandbox) Dir(workDir string) string {
+	if !s.Enabled() || len(s.Dirs) == 0 {
+		return workDir
+	}
+	return filepath.Join(workDir, s.Dirs[0])
+}
+
+// ExtraDirs are the remaining `dirs`, for CLIs with --add-dir
+func (s Sandbox) ExtraDirs(workDir string) []string {
+	if !s.Enabled() || len(s.Dirs) < 2 {
+		return nil
+	}
+	var dirs []string
+	for _, d := range s.Dirs[1:] {
+		dirs = append(dirs, filepath.Join(workDir, d))
+	}
+	return dirs
+}
+
+// DeniedTools are the write tools not explicitly allowed
+func (s Sandbox) DeniedTools() []string {
+	var denied []string
+	for _, t := range writeTools {
+		if !contains(s.AllowedTools, t) {
+			denied = append(denied, t)
+		}
+	}
+	return denied
+}
+
+// Environ returns the environment for a CLI process: the base set, vars
+// starting with the backend's name (CLAUDE_, MY_GATEWAY_, ...), the given
+// credential prefixes and `env:`. Returns nil (inherit everything) when
+// the sandbox is off.
+func (s Sandbox) Environ(name string, prefixes ...string) []string {
+	if !s.Enabled() {
+		return nil
+	}
+
+	prefixes = append(append([]string{envPrefix(name)}, prefixes...), baseEnvPrefixes...)
+
+	var env []string
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if contains(baseEnv, key) || contains(s.Env, key) || hasAnyPrefix(key, prefixes) {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+// envPrefix turns a backend name into an env var prefix: "my-gateway"
+// becomes "MY_GATEWAY_", since env var names can't hold "-" or "."
+func envPrefix(name string) string {
+	return strings.NewReplacer("-", "_", ".", "_").Replace(strings.ToUpper(name)) + "_"
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
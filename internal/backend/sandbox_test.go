@@ -0,0 +1,51 @@
+package backend
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEnvPrefix(t *testing.T) {
+	tests := map[string]string{
+		"claude":     "CLAUDE_",
+		"my-gateway": "MY_GATEWAY_",
+		"corp.llm":   "CORP_LLM_",
+	}
+	for name, want := range tests {
+		if got := envPrefix(name); got != want {
+			t.Errorf("envPrefix(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestEnvironKeepsHyphenatedBackendVars(t *testing.T) {
+	t.Setenv("MY_GATEWAY_TOKEN", "secret")
+	t.Setenv("DATABASE_URL", "postgres://prod")
+
+	env := Sandbox{Mode: SandboxReadOnly}.Environ("my-gateway")
+	if !slices.Contains(env, "MY_GATEWAY_TOKEN=secret") {
+		t.Error("MY_GATEWAY_TOKEN was stripped")
+	}
+	if slices.Contains(env, "DATABASE_URL=postgres://prod") {
+		t.Error("DATABASE_URL was passed through")
+	}
+}
+
+func TestSandboxCheck(t *testing.T) {
+	tests := []struct {
+		dirs []string
+		ok   bool
+	}{
+		{nil, true},
+		{[]string{"db", "app/models"}, true},
+		{[]string{"../other-repo"}, false},
+		{[]string{"db", "app/../../etc"}, false},
+		{[]string{"/etc"}, false},
+	}
+	for _, tt := range tests {
+		err := Sandbox{Mode: SandboxReadOnly, Dirs: tt.dirs}.Check()
+		if (err == nil) != tt.ok {
+			t.Errorf("Check(%q) = %v, want ok=%v", tt.dirs, err, tt.ok)
+		}
+	}
+}
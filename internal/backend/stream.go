@@ -0,0 +1,124 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// EventType identifies what a stream Event carries
+type EventType string
+
+const (
+	EventText     EventType = "text"     // Partial response text (delta)
+	EventActivity EventType = "activity" // Agent activity, e.g. "reading db/schema.rb"
+	EventDone     EventType = "done"     // Final result, always the last event on success
+	EventError    EventType = "error"    // Query failed, always the last event on failure
+)
+
+// Event is a single progress update from a streaming query
+type Event struct {
+	Type   EventType
+	Text   string // Delta for EventText, description for EventActivity
+	Path   string // File the agent touched (EventActivity), if any
+	Result Result // Set on EventDone
+	Err    error  // Set on EventError
+}
+
+// Streamer is implemented by backends that can report progress while
+// a query runs. The channel is closed after the done or error event.
+type Streamer interface {
+	QueryStream(ctx context.Context, prompt string, workDir string, opts Options) (<-chan Event, error)
+}
+
+// Stream runs a query with live progress when the backend supports it.
+// Other backends degrade to a blocking Query followed by a single event.
+func Stream(ctx context.Context, b Backend, prompt string, workDir string, opts Options) <-chan Event {
+	if s, ok := b.(Streamer); ok {
+		events, err := s.QueryStream(ctx, prompt, workDir, opts)
+		if err == nil {
+			return events
+		}
+		ch := make(chan Event, 1)
+		ch <- Event{Type: EventError, Err: err}
+		close(ch)
+		return ch
+	}
+
+	ch := make(chan Event, 1)
+	go func() {
+		defer close(ch)
+		result, err := b.Query(ctx, prompt, workDir, opts)
+		if err != nil {
+			ch <- Event{Type: EventError, Err: err}
+			return
+		}
+		ch <- Event{Type: EventDone, Result: result}
+	}()
+	return ch
+}
+
+// Collect drains a stream, calling fn for every progress event, and
+// returns the final result
+func Collect(events <-chan Event, fn func(Event)) (Result, error) {
+	for ev := range events {
+		switch ev.Type {
+		case EventDone:
+			return ev.Result, nil
+		case EventError:
+			return Result{}, ev.Err
+		default:
+			if fn != nil {
+				fn(ev)
+			}
+		}
+	}
+	return Result{}, fmt.Errorf("stream ended without a result")
+}
+
+// describeTool turns an agent tool call into a short activity line
+// and the file it touched, if any
+func describeTool(name string, input map[string]any, workDir string) (string, string) {
+	str := func(key string) string {
+		s, _ := input[key].(string)
+		return s
+	}
+
+	switch name {
+	case "Read", "NotebookRead":
+		p := relPath(str("file_path"), workDir)
+		return "reading " + p, p
+	case "Grep":
+		return fmt.Sprintf("searching for %q", truncate(str("pattern"), 40)), ""
+	case "Glob":
+		return "listing " + truncate(str("pattern"), 50), ""
+	case "LS":
+		return "listing " + relPath(str("path"), workDir), ""
+	case "Bash":
+		return "running " + truncate(str("command"), 50), ""
+	case "Task":
+		return "delegating: " + truncate(str("description"), 50), ""
+	default:
+		return "using " + name, ""
+	}
+}
+
+// relPath shortens absolute paths inside workDir for display
+func relPath(p, workDir string) string {
+	if p == "" || workDir == "" || !filepath.IsAbs(p) {
+		return p
+	}
+	if rel, err := filepath.Rel(workDir, p); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return p
+}
+
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > n {
+		return s[:n] + "…"
+	}
+	return s
+}
@@ -0,0 +1,221 @@
+// Package dialect names the SQL dialects qry knows and checks SQL for
+// constructs a target dialect doesn't support
+package dialect
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	PostgreSQL = "postgresql"
+	MySQL      = "mysql"
+	SQLite     = "sqlite"
+)
+
+// Names lists the dialects translate and the prompt templates know
+var Names = []string{PostgreSQL, MySQL, SQLite}
+
+var aliases = map[string]string{
+	"postgres": PostgreSQL,
+	"pg":       PostgreSQL,
+	"psql":     PostgreSQL,
+	"mariadb":  MySQL,
+	"sqlite3":  SQLite,
+}
+
+// Normalize returns the canonical name for a dialect, accepting common
+// aliases like "postgres" and "sqlite3"
+func Normalize(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if canonical, ok := aliases[name]; ok {
+		name = canonical
+	}
+	if !slices.Contains(Names, name) {
+		return "", fmt.Errorf("unknown dialect %q (use %s)", name, strings.Join(Names, ", "))
+	}
+	return name, nil
+}
+
+// Issue is a construct the target dialect doesn't support
+type Issue struct {
+	Construct string `json:"construct"`
+	Hint      string `json:"hint,omitempty"` // The target's equivalent
+}
+
+func (i Issue) String() string {
+	if i.Hint == "" {
+		return i.Construct
+	}
+	return i.Construct + ": " + i.Hint
+}
+
+// rule is a dialect-specific construct and the dialects that have it
+type rule struct {
+	construct string
+	pattern   *regexp.Regexp
+	supported []string
+	hints     map[string]string // Equivalent per target dialect
+}
+
+func fn(name string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)\b` + name + `\s*\(`)
+}
+
+var rules = []rule{
+	// MySQL
+	{"IFNULL()", fn("ifnull"), []string{MySQL, SQLite}, map[string]string{PostgreSQL: "COALESCE()"}},
+	{"IF()", fn("if"), []string{MySQL}, map[string]string{PostgreSQL: "CASE WHEN ... END", SQLite: "IIF() or CASE WHEN ... END"}},
+	{"DATE_FORMAT()", fn("date_format"), []string{MySQL}, map[string]string{PostgreSQL: "TO_CHAR()", SQLite: "strftime()"}},
+	{"STR_TO_DATE()", fn("str_to_date"), []string{MySQL}, map[string]string{PostgreSQL: "TO_DATE() or TO_TIMESTAMP()"}},
+	{"DATE_ADD()", fn("date_add"), []string{MySQL}, map[string]string{PostgreSQL: "+ INTERVAL '1 day'", SQLite: "date(x, '+1 day')"}},
+	{"DATE_SUB()", fn("date_sub"), []string{MySQL}, map[string]string{PostgreSQL: "- INTERVAL '1 day'", SQLite: "date(x, '-1 day')"}},
+	{"DATEDIFF()", fn("datediff"), []string{MySQL}, map[string]string{PostgreSQL: "date subtraction (a::date - b::date)", SQLite: "julianday(a) - julianday(b)"}},
+	{"TIMESTAMPDIFF()", fn("timestampdiff"), []string{MySQL}, map[string]string{PostgreSQL: "EXTRACT(EPOCH FROM a - b)", SQLite: "(julianday(a) - julianday(b)) * 86400"}},
+	{"CURDATE()", fn("curdate"), []string{MySQL}, map[string]string{PostgreSQL: "CURRENT_DATE", SQLite: "date('now')"}},
+	{"UNIX_TIMESTAMP()", fn("unix_timestamp"), []string{MySQL}, map[string]string{PostgreSQL: "EXTRACT(EPOCH FROM ...)", SQLite: "strftime('%s', ...)"}},
+	{"FROM_UNIXTIME()", fn("from_unixtime"), []string{MySQL}, map[string]string{PostgreSQL: "TO_TIMESTAMP()", SQLite: "datetime(x, 'unixepoch')"}},
+	{"GROUP_CONCAT()", fn("group_concat"), []string{MySQL, SQLite}, map[string]string{PostgreSQL: "STRING_AGG()"}},
+	{"LIMIT offset, count", regexp.MustCompile(`(?i)\blimit\s+\d+\s*,\s*\d+`), []string{MySQL, SQLite}, map[string]string{PostgreSQL: "LIMIT count OFFSET offset"}},
+	{"ON DUPLICATE KEY UPDATE", regexp.MustCompile(`(?i)\bon\s+duplicate\s+key\s+update\b`), []string{MySQL}, map[string]string{PostgreSQL: "ON CONFLICT (...) DO UPDATE", SQLite: "ON CONFLICT (...) DO UPDATE"}},
+	{"backtick identifiers", regexp.MustCompile("`"), []string{MySQL, SQLite}, map[string]string{PostgreSQL: `"double quotes"`}},
+	{"REGEXP / RLIKE", regexp.MustCompile(`(?i)\b(regexp|rlike)\b`), []string{MySQL}, map[string]string{PostgreSQL: "~ (or ~* to ignore case)", SQLite: "REGEXP, only with an extension loaded"}},
+	{"INSERT IGNORE", regexp.MustCompile(`(?i)\binsert\s+ignore\b`), []string{MySQL}, map[string]string{PostgreSQL: "INSERT ... ON CONFLICT DO NOTHING", SQLite: "INSERT OR IGNORE"}},
+	{"REPLACE INTO", regexp.MustCompile(`(?i)\breplace\s+into\b`), []string{MySQL, SQLite}, map[string]string{PostgreSQL: "INSERT ... ON CONFLICT (...) DO UPDATE"}},
+	{"FIND_IN_SET()", fn("find_in_set"), []string{MySQL}, map[string]string{PostgreSQL: "x = ANY(string_to_array(list, ','))", SQLite: "instr(',' || list || ',', ',' || x || ',') > 0"}},
+	{"SUBSTRING_INDEX()", fn("substring_index"), []string{MySQL}, map[string]string{PostgreSQL: "SPLIT_PART()", SQLite: "substr() with instr()"}},
+	{"LAST_INSERT_ID()", fn("last_insert_id"), []string{MySQL}, map[string]string{PostgreSQL: "RETURNING id, or LASTVAL()", SQLite: "last_insert_rowid()"}},
+
+	// PostgreSQL
+	{":: casts", regexp.MustCompile(`::\s*[a-zA-Z]`), []string{PostgreSQL}, map[string]string{MySQL: "CAST(x AS type)", SQLite: "CAST(x AS type)"}},
+	{"ILIKE", regexp.MustCompile(`(?i)\bilike\b`), []string{PostgreSQL}, map[string]string{MySQL: "LIKE (case-insensitive with the default collation)", SQLite: "LIKE (case-insensitive for ASCII)"}},
+	{"DATE_TRUNC()", fn("date_trunc"), []string{PostgreSQL}, map[string]string{MySQL: "DATE_FORMAT() or DATE()", SQLite: "strftime() or date()"}},
+	{"STRING_AGG()", fn("string_agg"), []string{PostgreSQL}, map[string]string{MySQL: "GROUP_CONCAT(x SEPARATOR ',')", SQLite: "GROUP_CONCAT(x, ',')"}},
+	{"ARRAY_AGG()", fn("array_agg"), []string{PostgreSQL}, map[string]string{MySQL: "JSON_ARRAYAGG()", SQLite: "json_group_array()"}},
+	{"TO_CHAR()", fn("to_char"), []string{PostgreSQL}, map[string]string{MySQL: "DATE_FORMAT()", SQLite: "strftime()"}},
+	{"GENERATE_SERIES()", fn("generate_series"), []string{PostgreSQL}, map[string]string{MySQL: "a recursive CTE", SQLite: "a recursive CTE"}},
+	{"DISTINCT ON", regexp.MustCompile(`(?i)\bdistinct\s+on\s*\(`), []string{PostgreSQL}, map[string]string{MySQL: "ROW_NUMBER() OVER (PARTITION BY ...)", SQLite: "ROW_NUMBER() OVER (PARTITION BY ...)"}},
+	{"FILTER (WHERE ...)", regexp.MustCompile(`(?i)\bfilter\s*\(\s*where\b`), []string{PostgreSQL, SQLite}, map[string]string{MySQL: "SUM(CASE WHEN ... THEN 1 ELSE 0 END)"}},
+	{"INTERVAL '...'", regexp.MustCompile(`(?i)\binterval\s*''`), []string{PostgreSQL}, map[string]string{MySQL: "INTERVAL 1 DAY", SQLite: "date(x, '+1 day')"}},
+	{"RETURNING", regexp.MustCompile(`(?i)\breturning\b`), []string{PostgreSQL, SQLite}, nil},
+	{"~ regex match", regexp.MustCompile(`[^!~]~\*?\s*'`), []string{PostgreSQL}, map[string]string{MySQL: "REGEXP", SQLite: "REGEXP (needs an extension)"}},
+
+	// SQLite
+	{"strftime()", fn("strftime"), []string{SQLite}, map[string]string{PostgreSQL: "TO_CHAR()", MySQL: "DATE_FORMAT()"}},
+	{"julianday()", fn("julianday"), []string{SQLite}, map[string]string{PostgreSQL: "date subtraction", MySQL: "DATEDIFF()"}},
+	{"datetime()", fn("datetime"), []string{SQLite}, map[string]string{PostgreSQL: "NOW() or a timestamp literal", MySQL: "NOW() or a datetime literal"}},
+	{"IIF()", fn("iif"), []string{SQLite}, map[string]string{PostgreSQL: "CASE WHEN ... END", MySQL: "IF()"}},
+	{"NOW()", fn("now"), []string{PostgreSQL, MySQL}, map[string]string{SQLite: "datetime('now')"}},
+}
+
+// standard holds forms a rule's pattern also matches that are portable,
+// e.g. INTERVAL '1' DAY, which MySQL accepts
+var standard = map[string]*regexp.Regexp{
+	"INTERVAL '...'": regexp.MustCompile(`(?i)\binterval\s*''\s*(year|quarter|month|week|day|hour|minute|second|microsecond)\b`),
+}
+
+// Check returns the constructs in sql, written for from, that target
+// doesn't support. String literals and comments are ignored. To check
+// SQL already in the target dialect, pass it as both.
+func Check(sql, from, target string) []Issue {
+	code := stripLiterals(sql, from)
+
+	var issues []Issue
+
+	// MySQL reads "x" as a string; elsewhere it's an identifier
+	if strings.Contains(code, `"`) {
+		switch {
+		case from == MySQL && target != MySQL:
+			issues = append(issues, Issue{Construct: `"double-quoted" strings`, Hint: "'single quotes'"})
+		case from != MySQL && target == MySQL:
+			issues = append(issues, Issue{Construct: `"double-quoted" identifiers`, Hint: "`backticks`"})
+		}
+	}
+
+	for _, r := range rules {
+		if slices.Contains(r.supported, target) {
+			continue
+		}
+		n := len(r.pattern.FindAllStringIndex(code, -1))
+		if s, ok := standard[r.construct]; ok {
+			n -= len(s.FindAllStringIndex(code, -1))
+		}
+		if n <= 0 {
+			continue
+		}
+		issues = append(issues, Issue{Construct: r.construct, Hint: r.hints[target]})
+	}
+	return issues
+}
+
+// dollarTag matches the opening of a Postgres dollar-quoted string, $$
+// or $tag$. $1 is a parameter, so tags can't start with a digit.
+var dollarTag = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`)
+
+// stripLiterals blanks out string literals and comments so their text
+// isn't mistaken for code. Quotes are kept, so "INTERVAL '" still matches.
+// In MySQL, "..." is a string too and backslash escapes a quote. In
+// Postgres, $$...$$ and $tag$...$tag$ bodies are strings too.
+func stripLiterals(sql, from string) string {
+	mysql := from == MySQL
+
+	var sb strings.Builder
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' && mysql:
+			end := literalEnd(sql[i+1:], c, mysql)
+			sb.WriteByte(c)
+			sb.WriteByte(c)
+			i += end + 1
+		case c == '$' && from == PostgreSQL && (i == 0 || !isIdentByte(sql[i-1])) && dollarTag.MatchString(sql[i:]):
+			tag := dollarTag.FindString(sql[i:])
+			sb.WriteString(tag)
+			end := strings.Index(sql[i+len(tag):], tag)
+			if end == -1 {
+				return sb.String()
+			}
+			sb.WriteString(tag)
+			i += len(tag) + end + len(tag) - 1
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end == -1 {
+				return sb.String()
+			}
+			i += end - 1
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end == -1 {
+				return sb.String()
+			}
+			sb.WriteByte(' ')
+			i += end + 3
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// isIdentByte reports whether c can continue an identifier; Postgres
+// allows $ there, as in col$1
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// literalEnd returns the index of the quote closing a literal in s, or
+// len(s)-1 if it isn't closed. Doubled quotes close and reopen, which
+// leaves the text blanked all the same.
+func literalEnd(s string, quote byte, backslash bool) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case backslash && s[i] == '\\':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return len(s) - 1
+}
@@ -0,0 +1,74 @@
+package dialect
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		from, to string
+		want     []string // Constructs, in rule order
+	}{
+		{"portable", "SELECT id, COALESCE(name, '') FROM users WHERE id = 1", MySQL, PostgreSQL, nil},
+		{"ifnull", "SELECT IFNULL(a, 0) FROM t", MySQL, PostgreSQL, []string{"IFNULL()"}},
+		{"ifnull in sqlite", "SELECT IFNULL(a, 0) FROM t", MySQL, SQLite, nil},
+		{"limit offset", "SELECT * FROM t LIMIT 10, 20", MySQL, PostgreSQL, []string{"LIMIT offset, count"}},
+		{"regexp", "SELECT * FROM t WHERE name REGEXP '^a'", MySQL, PostgreSQL, []string{"REGEXP / RLIKE"}},
+		{"rlike", "SELECT * FROM t WHERE name NOT RLIKE '^a'", MySQL, PostgreSQL, []string{"REGEXP / RLIKE"}},
+		{"find_in_set", "SELECT * FROM t WHERE FIND_IN_SET('a', tags)", MySQL, PostgreSQL, []string{"FIND_IN_SET()"}},
+		{"insert ignore", "INSERT IGNORE INTO t (a) VALUES (1)", MySQL, PostgreSQL, []string{"INSERT IGNORE"}},
+		{"replace into", "REPLACE INTO t (a) VALUES (1)", MySQL, PostgreSQL, []string{"REPLACE INTO"}},
+		{"replace function", "SELECT REPLACE(a, 'x', 'y') FROM t", MySQL, PostgreSQL, nil},
+		{"substring_index", "SELECT SUBSTRING_INDEX(email, '@', -1) FROM users", MySQL, PostgreSQL, []string{"SUBSTRING_INDEX()"}},
+		{"last_insert_id", "SELECT LAST_INSERT_ID()", MySQL, PostgreSQL, []string{"LAST_INSERT_ID()"}},
+		{"double-quoted string", `SELECT * FROM t WHERE status = "active"`, MySQL, PostgreSQL, []string{`"double-quoted" strings`}},
+		{"double-quoted identifier", `SELECT "order" FROM t`, PostgreSQL, MySQL, []string{`"double-quoted" identifiers`}},
+		{"double-quoted identifier stays", `SELECT "order" FROM t`, PostgreSQL, PostgreSQL, nil},
+		{"backslash escape", `SELECT 'it\'s', IFNULL(a, 0) FROM t`, MySQL, PostgreSQL, []string{"IFNULL()"}},
+		{"function in string", "SELECT 'IFNULL(a, 0)' FROM t", MySQL, PostgreSQL, nil},
+		{"function in comment", "SELECT a FROM t -- was IFNULL(a, 0)\nWHERE b = 1", MySQL, PostgreSQL, nil},
+		{"function in double-quoted string", `SELECT "IFNULL(a, 0)" FROM t`, MySQL, PostgreSQL, []string{`"double-quoted" strings`}},
+		{"casts", "SELECT created_at::date FROM t", PostgreSQL, MySQL, []string{":: casts"}},
+		{"ilike and distinct on", "SELECT DISTINCT ON (a) a FROM t WHERE b ILIKE 'x%'", PostgreSQL, MySQL, []string{"ILIKE", "DISTINCT ON"}},
+		{"postgres interval", "SELECT now() - INTERVAL '7 days'", PostgreSQL, MySQL, []string{"INTERVAL '...'"}},
+		{"standard interval", "SELECT now() - INTERVAL '7' DAY", MySQL, MySQL, nil},
+		{"now in sqlite", "SELECT NOW()", MySQL, SQLite, []string{"NOW()"}},
+		{"dollar quoted", "SELECT $$it's ILIKE 'x'$$, a::text FROM t", PostgreSQL, MySQL, []string{":: casts"}},
+		{"tagged dollar quote", "SELECT $body$ a $$ ILIKE $body$ FROM t WHERE b ILIKE 'x'", PostgreSQL, MySQL, []string{"ILIKE"}},
+		{"unclosed dollar quote", "SELECT $$ ILIKE", PostgreSQL, MySQL, nil},
+		{"parameters aren't dollar quotes", "SELECT * FROM t WHERE a = $1 AND b ILIKE $2", PostgreSQL, MySQL, []string{"ILIKE"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, i := range Check(tt.sql, tt.from, tt.to) {
+				got = append(got, i.Construct)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Check(%q, %s, %s) = %q, want %q", tt.sql, tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"postgresql": PostgreSQL,
+		"Postgres":   PostgreSQL,
+		"pg":         PostgreSQL,
+		"mariadb":    MySQL,
+		"sqlite3":    SQLite,
+	}
+	for in, want := range tests {
+		if got, err := Normalize(in); err != nil || got != want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := Normalize("oracle"); err == nil {
+		t.Error("Normalize(oracle) succeeded")
+	}
+}
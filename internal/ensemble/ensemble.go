@@ -0,0 +1,118 @@
+// Package ensemble runs the same prompt against several backends and
+// compares the SQL they produce.
+package ensemble
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/amansingh-afk/qry/internal/backend"
+)
+
+// Candidate is one backend's answer
+type Candidate struct {
+	Backend    string        `json:"backend"`
+	Model      string        `json:"model,omitempty"`
+	SQL        string        `json:"sql,omitempty"`
+	Normalized string        `json:"-"`
+	Duration   time.Duration `json:"-"`
+	Seconds    float64       `json:"duration"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Group is a set of backends that produced equivalent SQL
+type Group struct {
+	SQL      string   `json:"sql"` // As written by the first backend in the group
+	Backends []string `json:"backends"`
+	Diff     []string `json:"diff,omitempty"` // Clause differences vs the largest group
+}
+
+// Report summarizes agreement across candidates
+type Report struct {
+	Candidates []Candidate `json:"candidates"`
+	Groups     []Group     `json:"groups"`    // Largest first
+	Succeeded  int         `json:"succeeded"` // Backends that returned SQL
+	Consensus  bool        `json:"consensus"` // At least two succeeded and all of them agree
+}
+
+// Compared reports whether at least two backends returned SQL, so there
+// was something to compare
+func (r Report) Compared() bool {
+	return r.Succeeded >= 2
+}
+
+// QueryFunc runs the prompt against one backend and returns extracted SQL
+// and the model used
+type QueryFunc func(ctx context.Context, b backend.Backend) (sql string, model string, err error)
+
+// Run fans out to all backends concurrently. Results keep the input order.
+func Run(ctx context.Context, backends []backend.Backend, query QueryFunc) []Candidate {
+	candidates := make([]Candidate, len(backends))
+
+	var wg sync.WaitGroup
+	for i, b := range backends {
+		wg.Add(1)
+		go func(i int, b backend.Backend) {
+			defer wg.Done()
+
+			start := time.Now()
+			sql, model, err := query(ctx, b)
+			elapsed := time.Since(start)
+
+			c := Candidate{
+				Backend:  b.Name(),
+				Model:    model,
+				SQL:      sql,
+				Duration: elapsed,
+				Seconds:  elapsed.Round(100 * time.Millisecond).Seconds(),
+			}
+			if err != nil {
+				c.SQL = ""
+				c.Error = err.Error()
+			}
+			candidates[i] = c
+		}(i, b)
+	}
+	wg.Wait()
+
+	return candidates
+}
+
+// Compare groups candidates by normalized SQL and describes how the
+// minority answers differ from the majority
+func Compare(candidates []Candidate) Report {
+	report := Report{Candidates: candidates}
+
+	index := make(map[string]int)
+	for i := range candidates {
+		c := &candidates[i]
+		if c.Error != "" || c.SQL == "" {
+			continue
+		}
+		c.Normalized = Normalize(c.SQL)
+		report.Succeeded++
+
+		if gi, ok := index[c.Normalized]; ok {
+			report.Groups[gi].Backends = append(report.Groups[gi].Backends, c.Backend)
+			continue
+		}
+		index[c.Normalized] = len(report.Groups)
+		report.Groups = append(report.Groups, Group{SQL: c.SQL, Backends: []string{c.Backend}})
+	}
+
+	sort.SliceStable(report.Groups, func(i, j int) bool {
+		return len(report.Groups[i].Backends) > len(report.Groups[j].Backends)
+	})
+
+	if len(report.Groups) > 1 {
+		base := Normalize(report.Groups[0].SQL)
+		for i := 1; i < len(report.Groups); i++ {
+			report.Groups[i].Diff = Diff(base, Normalize(report.Groups[i].SQL))
+		}
+	}
+
+	report.Consensus = report.Compared() && len(report.Groups) == 1
+	return report
+}
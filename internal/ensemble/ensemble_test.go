@@ -0,0 +1,56 @@
+package ensemble
+
+import "testing"
+
+func TestCompareConsensus(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []Candidate
+		succeeded  int
+		groups     int
+		consensus  bool
+	}{
+		{
+			name: "all agree",
+			candidates: []Candidate{
+				{Backend: "claude", SQL: "SELECT * FROM users;"},
+				{Backend: "codex", SQL: "select *\nfrom users"},
+			},
+			succeeded: 2, groups: 1, consensus: true,
+		},
+		{
+			name: "differ",
+			candidates: []Candidate{
+				{Backend: "claude", SQL: "SELECT * FROM users"},
+				{Backend: "codex", SQL: "SELECT id FROM users"},
+			},
+			succeeded: 2, groups: 2, consensus: false,
+		},
+		{
+			name: "only one succeeded",
+			candidates: []Candidate{
+				{Backend: "claude", SQL: "SELECT * FROM users"},
+				{Backend: "codex", Error: "codex: exit status 1"},
+			},
+			succeeded: 1, groups: 1, consensus: false,
+		},
+		{
+			name: "none succeeded",
+			candidates: []Candidate{
+				{Backend: "claude", Error: "timeout"},
+				{Backend: "codex", Error: "codex: exit status 1"},
+			},
+			succeeded: 0, groups: 0, consensus: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Compare(tt.candidates)
+			if r.Succeeded != tt.succeeded || len(r.Groups) != tt.groups || r.Consensus != tt.consensus {
+				t.Errorf("got succeeded=%d groups=%d consensus=%v, want %d %d %v",
+					r.Succeeded, len(r.Groups), r.Consensus, tt.succeeded, tt.groups, tt.consensus)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package ensemble
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Normalize rewrites SQL into a canonical form so that formatting,
+// comments, keyword case and trailing semicolons don't count as
+// differences. String literals and quoted identifiers are kept as-is.
+func Normalize(sql string) string {
+	var out strings.Builder
+	space := false
+
+	emit := func(s string) {
+		if space && out.Len() > 0 {
+			out.WriteByte(' ')
+		}
+		space = false
+		out.WriteString(s)
+	}
+
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+
+		switch {
+		// -- line comment
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+			space = true
+
+		// /* block comment */
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 3
+			}
+			space = true
+
+		// 'literal', "identifier", `identifier`
+		case c == '\'' || c == '"' || c == '`':
+			j := i + 1
+			for j < len(sql) {
+				if sql[j] == c {
+					// Doubled quote is an escape
+					if j+1 < len(sql) && sql[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			if j >= len(sql) {
+				j = len(sql) - 1
+			}
+			emit(sql[i : j+1])
+			i = j
+
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+
+		case isWordByte(c):
+			j := i
+			for j < len(sql) && isWordByte(sql[j]) {
+				j++
+			}
+			emit(strings.ToLower(sql[i:j]))
+			i = j - 1
+
+		// No space around punctuation and operators
+		default:
+			space = false
+			out.WriteByte(c)
+			// Space after an operator isn't significant either
+			for i+1 < len(sql) && (sql[i+1] == ' ' || sql[i+1] == '\t' || sql[i+1] == '\n' || sql[i+1] == '\r') {
+				i++
+			}
+		}
+	}
+
+	return strings.TrimRight(strings.TrimSpace(out.String()), "; ")
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// Top-level clauses compared by Diff, in query order
+var clauses = []string{
+	"with", "select", "from", "join", "where", "group by",
+	"having", "order by", "limit", "offset",
+}
+
+// Clause keywords recognized by splitClauses; all joins count as "join"
+var clauseKeywords = append([]string{"left join", "right join", "inner join", "full join", "cross join"}, clauses...)
+
+// splitClauses breaks normalized SQL into its top-level clauses.
+// Joins are collected together under "join".
+func splitClauses(sql string) map[string]string {
+	parts := make(map[string]string)
+	current := ""
+	depth := 0
+	start := 0
+
+	flush := func(end int) {
+		text := strings.TrimSpace(sql[start:end])
+		if current == "" || text == "" {
+			return
+		}
+		if prev, ok := parts[current]; ok {
+			parts[current] = prev + " " + text
+		} else {
+			parts[current] = text
+		}
+	}
+
+	for i := 0; i < len(sql); i++ {
+		switch sql[i] {
+		case '(':
+			depth++
+			continue
+		case ')':
+			depth--
+			continue
+		case '\'':
+			if j := strings.IndexByte(sql[i+1:], '\''); j >= 0 {
+				i += j + 1
+			}
+			continue
+		}
+
+		if depth != 0 || (i > 0 && isWordByte(sql[i-1])) {
+			continue
+		}
+
+		for _, kw := range clauseKeywords {
+			end := i + len(kw)
+			if end > len(sql) || sql[i:end] != kw || (end < len(sql) && isWordByte(sql[end])) {
+				continue
+			}
+			flush(i)
+			current = kw
+			if strings.HasSuffix(kw, "join") {
+				current = "join"
+			}
+			start = i
+			i = end - 1
+			break
+		}
+	}
+	flush(len(sql))
+
+	return parts
+}
+
+// Diff lists clause-level differences between two normalized queries
+func Diff(base, other string) []string {
+	a := splitClauses(base)
+	b := splitClauses(other)
+
+	var diffs []string
+	for _, clause := range clauses {
+		x, y := a[clause], b[clause]
+		if x == y {
+			continue
+		}
+		switch {
+		case x == "":
+			diffs = append(diffs, fmt.Sprintf("adds %s", y))
+		case y == "":
+			diffs = append(diffs, fmt.Sprintf("drops %s", x))
+		default:
+			diffs = append(diffs, fmt.Sprintf("%s instead of %s", y, x))
+		}
+	}
+
+	// Same clauses, different shape (e.g. UNION, subquery placement)
+	if len(diffs) == 0 && base != other {
+		diffs = append(diffs, "differs in structure")
+	}
+
+	return diffs
+}
@@ -4,36 +4,188 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
 
+	"github.com/amansingh-afk/qry/internal/backend"
+	"github.com/amansingh-afk/qry/internal/dialect"
+	"github.com/amansingh-afk/qry/internal/ensemble"
+	"github.com/amansingh-afk/qry/internal/prompt"
 	"github.com/charmbracelet/lipgloss"
 )
 
 var (
-	sqlStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#50FA7B"))
-	dimStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#6272A4"))
+	sqlStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#50FA7B"))
+	dimStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#6272A4"))
+	agreeStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#50FA7B")).Bold(true)
+	differStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#F1FA8C"))
 )
 
 type Result struct {
-	SQL     string `json:"sql"`
-	Backend string `json:"backend"`
-	Model   string `json:"model,omitempty"`
-	Dialect string `json:"dialect,omitempty"`
+	SQL          string             `json:"sql"`
+	Outcome      prompt.OutcomeKind `json:"outcome"`              // sql, refusal, clarification or no_sql
+	Message      string             `json:"message,omitempty"`    // The refusal or question when there's no SQL
+	Options      []string           `json:"options,omitempty"`    // Suggested answers to the question
+	Statements   []string           `json:"statements,omitempty"` // Set when the SQL has more than one
+	Backend      string             `json:"backend"`              // Backend that actually answered
+	Model        string             `json:"model,omitempty"`
+	Dialect      string             `json:"dialect,omitempty"`
+	FallbackFrom []string           `json:"fallback_from,omitempty"` // Backends that failed first
+	Usage        *backend.Usage     `json:"usage,omitempty"`
+	FilesRead    []string           `json:"files_read,omitempty"` // Files the agent read, relative to the repo
+
+	DefinitionsApplied []prompt.Definition `json:"definitions_applied,omitempty"` // Glossary terms the question used
+
+	// Set with `structured: true`
+	Explanation string   `json:"explanation,omitempty"`
+	Assumptions []string `json:"assumptions,omitempty"`
+	TablesUsed  []string `json:"tables_used,omitempty"`
+	Confidence  *float64 `json:"confidence,omitempty"`
 }
 
-func JSON(w io.Writer, sql, backend, model, dialect string) {
+// SetAnswer copies a structured answer's fields into the result
+func (r *Result) SetAnswer(a *prompt.Answer) {
+	if a == nil {
+		return
+	}
+	r.Explanation = a.Explanation
+	r.Assumptions = a.Assumptions
+	r.TablesUsed = a.TablesUsed
+	r.Confidence = &a.Confidence
+}
+
+func JSON(w io.Writer, r Result) {
 	enc := json.NewEncoder(w)
 	enc.SetIndent("", "  ")
-	_ = enc.Encode(Result{
-		SQL:     sql,
-		Backend: backend,
-		Model:   model,
-		Dialect: dialect,
-	})
+	enc.SetEscapeHTML(false) // Keep < and > readable in SQL
+	_ = enc.Encode(r)
 }
 
-func Pretty(w io.Writer, sql, backend, model string) {
+// Pretty prints the SQL and a footer. A structured answer adds its
+// explanation and assumptions between them.
+func Pretty(w io.Writer, sql, backendName, model string, usage backend.Usage, answer *prompt.Answer) {
+	footer := "— " + backendName + "/" + model
+	if answer != nil {
+		footer += fmt.Sprintf(" · confidence %.0f%%", answer.Confidence*100)
+	}
+	if summary := usage.Summary(); summary != "" {
+		footer += " · " + summary
+	}
+
 	_, _ = fmt.Fprintln(w)
 	_, _ = fmt.Fprintln(w, sqlStyle.Render(sql))
 	_, _ = fmt.Fprintln(w)
-	_, _ = fmt.Fprintln(w, dimStyle.Render("— "+backend+"/"+model))
+	if answer != nil {
+		_, _ = fmt.Fprintln(w, answer.Explanation)
+		for _, a := range answer.Assumptions {
+			_, _ = fmt.Fprintln(w, dimStyle.Render("  assumes: "+a))
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+	_, _ = fmt.Fprintln(w, dimStyle.Render(footer))
+}
+
+// Explanation is `qry explain --json` output
+type Explanation struct {
+	Explanation   string         `json:"explanation"`
+	Backend       string         `json:"backend"`
+	Model         string         `json:"model,omitempty"`
+	Dialect       string         `json:"dialect,omitempty"`
+	SecurityFlags []string       `json:"security_flags,omitempty"` // Excluded objects the SQL uses
+	Usage         *backend.Usage `json:"usage,omitempty"`
+}
+
+// ExplainJSON writes an explanation as JSON
+func ExplainJSON(w io.Writer, e Explanation) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(e)
+}
+
+// ExplainPretty prints an explanation and a footer
+func ExplainPretty(w io.Writer, text, backendName, model string, usage backend.Usage) {
+	footer := "— " + backendName + "/" + model
+	if summary := usage.Summary(); summary != "" {
+		footer += " · " + summary
+	}
+
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, strings.TrimSpace(text))
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, dimStyle.Render(footer))
+}
+
+// Translation is `qry translate --json` output
+type Translation struct {
+	SQL     string          `json:"sql"`
+	From    string          `json:"from"`
+	To      string          `json:"to"`
+	Backend string          `json:"backend"`
+	Model   string          `json:"model,omitempty"`
+	Issues  []dialect.Issue `json:"issues,omitempty"` // Constructs the target still lacks
+	Usage   *backend.Usage  `json:"usage,omitempty"`
+
+	Warning         string `json:"warning,omitempty"`
+	SecurityWarning string `json:"security_warning,omitempty"`
+}
+
+// TranslateJSON writes a translation as JSON
+func TranslateJSON(w io.Writer, t Translation) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(t)
+}
+
+// EnsembleJSON writes an ensemble comparison as JSON
+func EnsembleJSON(w io.Writer, report ensemble.Report, dialect string) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(struct {
+		ensemble.Report
+		Dialect string `json:"dialect,omitempty"`
+	}{report, dialect})
+}
+
+// EnsemblePretty shows each distinct answer with the backends behind it,
+// majority first, followed by failures
+func EnsemblePretty(w io.Writer, report ensemble.Report) {
+	total := len(report.Candidates)
+
+	for i, g := range report.Groups {
+		_, _ = fmt.Fprintln(w)
+
+		label := fmt.Sprintf("%s (%d/%d)", strings.Join(g.Backends, ", "), len(g.Backends), total)
+		switch {
+		case !report.Compared():
+			_, _ = fmt.Fprintln(w, differStyle.Render("? "+label+", no comparison: only one backend returned SQL"))
+		case report.Consensus:
+			_, _ = fmt.Fprintln(w, agreeStyle.Render("✓ all agree: "+label))
+		case i == 0:
+			_, _ = fmt.Fprintln(w, dimStyle.Render("● "+label))
+		default:
+			_, _ = fmt.Fprintln(w, differStyle.Render("≠ "+label))
+		}
+
+		_, _ = fmt.Fprintln(w, sqlStyle.Render(g.SQL))
+
+		for _, d := range g.Diff {
+			_, _ = fmt.Fprintln(w, differStyle.Render("  · "+d))
+		}
+	}
+
+	for _, c := range report.Candidates {
+		if c.Error != "" {
+			_, _ = fmt.Fprintln(w)
+			_, _ = fmt.Fprintln(w, dimStyle.Render("✗ "+c.Backend+": "+firstLine(c.Error)))
+		}
+	}
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
 }
@@ -0,0 +1,95 @@
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Answer is the reply asked for when `structured: true`. The SQL comes
+// back in its own field, so explanatory text can't leak into it.
+type Answer struct {
+	SQL         string   `json:"sql"`
+	Explanation string   `json:"explanation"`
+	Assumptions []string `json:"assumptions"`
+	TablesUsed  []string `json:"tables_used"`
+	Confidence  float64  `json:"confidence"` // 0 to 1
+
+	Clarification *Clarification `json:"clarification,omitempty"` // Asked instead of writing SQL
+}
+
+// Structured reports whether backends are asked for a JSON Answer
+func Structured() bool {
+	return viper.GetBool("structured")
+}
+
+const answerInstructions = `
+
+Reply with only a JSON object and no other text. This overrides any instruction to output only SQL:
+{"sql": "<the query>", "explanation": "<one or two sentences on how it answers the request>", "assumptions": ["<anything you had to assume>"], "tables_used": ["<table>"], "confidence": <0 to 1>}
+
+If the request is ambiguous in a way that changes the result (for example fiscal vs calendar quarter, or gross vs net revenue), don't guess: leave "sql" empty and add "clarification": {"question": "<one question>", "options": ["<option>", "<option>"]}.`
+
+const answerReminder = `
+
+Reply with the same JSON object as before (sql, explanation, assumptions, tables_used, confidence) and no other text.`
+
+// ParseAnswer reads a structured reply. Code fences and text around the
+// object are tolerated; a missing sql or explanation, or a confidence
+// outside 0 to 1, is not.
+func ParseAnswer(response string) (Answer, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end < start {
+		return Answer{}, fmt.Errorf("no JSON object in the reply")
+	}
+
+	var raw struct {
+		Answer
+		Confidence *float64 `json:"confidence"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &raw); err != nil {
+		return Answer{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	a := raw.Answer
+	a.SQL = ExtractSQL(a.SQL) // Models sometimes fence the SQL inside the field
+	a.Explanation = strings.TrimSpace(a.Explanation)
+
+	switch {
+	case a.SQL == "":
+		return Answer{}, fmt.Errorf(`"sql" is missing or empty`)
+	case a.Explanation == "":
+		return Answer{}, fmt.Errorf(`"explanation" is missing or empty`)
+	case raw.Confidence == nil:
+		return Answer{}, fmt.Errorf(`"confidence" is missing`)
+	case *raw.Confidence < 0 || *raw.Confidence > 1:
+		return Answer{}, fmt.Errorf(`"confidence" must be between 0 and 1, got %g`, *raw.Confidence)
+	}
+	a.Confidence = *raw.Confidence
+	return a, nil
+}
+
+// RepairPrompt asks the model to fix an invalid structured reply
+func RepairPrompt(response string, err error) string {
+	return fmt.Sprintf("Your previous reply could not be used: %s.\n\nPrevious reply:\n%s%s",
+		err, strings.TrimSpace(response), answerReminder)
+}
+
+// ResolveAnswer parses a structured reply and, if it's invalid, gives the
+// model one chance to fix it. ask sends the repair prompt and returns
+// the new reply.
+func ResolveAnswer(response string, ask func(repair string) (string, error)) (Answer, error) {
+	a, err := ParseAnswer(response)
+	if err == nil {
+		return a, nil
+	}
+
+	repaired, askErr := ask(RepairPrompt(response, err))
+	if askErr != nil {
+		return Answer{}, fmt.Errorf("%w (repair failed: %v)", err, askErr)
+	}
+	return ParseAnswer(repaired)
+}
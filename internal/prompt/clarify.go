@@ -0,0 +1,61 @@
+package prompt
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Clarification is a question the model asks instead of guessing, with
+// the answers it has in mind
+type Clarification struct {
+	Question string   `json:"question"`
+	Options  []string `json:"options,omitempty"`
+}
+
+// The protocol BuildSQL asks for:
+//
+//	CLARIFY: Fiscal or calendar quarter?
+//	- Fiscal quarter (starts February)
+//	- Calendar quarter
+const clarifyInstructions = `
+
+If the request is ambiguous in a way that changes the result (for example fiscal vs calendar quarter, or gross vs net revenue), don't guess. Reply with only a question and the likely answers:
+CLARIFY: <one question>
+- <option>
+- <option>`
+
+var clarifyMarker = regexp.MustCompile(`(?im)^\W*CLARIFY:\s*(.*)$`)
+var optionLine = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s+(.+?)\s*$`)
+
+// parseClarification splits a clarifying reply into the question and
+// any options listed as bullets or numbers
+func parseClarification(text string) Clarification {
+	var c Clarification
+	var question []string
+
+	if m := clarifyMarker.FindStringSubmatchIndex(text); m != nil {
+		question = append(question, strings.TrimSpace(text[m[2]:m[3]]))
+		text = text[m[1]:]
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		if m := optionLine.FindStringSubmatch(line); m != nil {
+			c.Options = append(c.Options, m[1])
+			continue
+		}
+		if line = strings.TrimSpace(line); line != "" && len(c.Options) == 0 {
+			question = append(question, line)
+		}
+	}
+
+	c.Question = strings.TrimSpace(strings.Join(question, " "))
+	return c
+}
+
+// ClarifiedQuery folds the answer to a clarifying question into the
+// original request. It reads the same with or without a session, so
+// backends that don't keep one still get the whole request.
+func ClarifiedQuery(query, question, answer string) string {
+	return strings.TrimSpace(query) + "\n\nYou asked: " + strings.TrimSpace(question) +
+		"\nAnswer: " + strings.TrimSpace(answer)
+}
@@ -0,0 +1,182 @@
+package prompt
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// ExamplesFile holds verified question → SQL pairs, alongside `examples:`
+// in .qry.yaml:
+//
+//   - question: monthly revenue
+//     sql: SELECT date_trunc('month', paid_at), sum(amount_cents) / 100.0 FROM payments GROUP BY 1
+const ExamplesFile = ".qry/examples.yaml"
+
+// DefaultExamplesLimit is how many examples go into a prompt unless
+// `examples_limit` says otherwise
+const DefaultExamplesLimit = 3
+
+// Example is a verified question and its canonical SQL
+type Example struct {
+	Question string `yaml:"question" mapstructure:"question"`
+	SQL      string `yaml:"sql" mapstructure:"sql"`
+}
+
+// LoadExamples reads `examples:` and .qry/examples.yaml. The file may be
+// a list or have the list under `examples:`.
+func LoadExamples(workDir string) ([]Example, error) {
+	var examples []Example
+	if err := viper.UnmarshalKey("examples", &examples); err != nil {
+		return nil, fmt.Errorf("examples: %w", err)
+	}
+
+	path := filepath.Join(workDir, ExamplesFile)
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		var file []Example
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			var wrapped struct {
+				Examples []Example `yaml:"examples"`
+			}
+			if err2 := yaml.Unmarshal(data, &wrapped); err2 != nil {
+				return nil, fmt.Errorf("%s: %w", ExamplesFile, err)
+			}
+			file = wrapped.Examples
+		}
+		examples = append(examples, file...)
+	}
+
+	for i, ex := range examples {
+		if strings.TrimSpace(ex.Question) == "" || strings.TrimSpace(ex.SQL) == "" {
+			return nil, fmt.Errorf("example %d: needs both question and sql", i+1)
+		}
+	}
+	return examples, nil
+}
+
+// SelectExamples returns up to limit examples most relevant to query,
+// ranked by BM25 over the question and SQL. Examples sharing no terms
+// with the query are never selected.
+func SelectExamples(examples []Example, query string, limit int) []Example {
+	terms := tokenize(query)
+	if len(examples) == 0 || len(terms) == 0 || limit <= 0 {
+		return nil
+	}
+
+	docs := make([][]string, len(examples))
+	df := make(map[string]int)
+	total := 0
+	for i, ex := range examples {
+		docs[i] = tokenize(ex.Question + " " + ex.SQL)
+		total += len(docs[i])
+
+		seen := make(map[string]bool)
+		for _, t := range docs[i] {
+			if !seen[t] {
+				seen[t] = true
+				df[t]++
+			}
+		}
+	}
+	avgLen := float64(total) / float64(len(docs))
+
+	const k1, b = 1.2, 0.75
+	n := float64(len(docs))
+
+	type scored struct {
+		idx   int
+		score float64
+	}
+	var ranked []scored
+	for i, doc := range docs {
+		tf := make(map[string]int)
+		for _, t := range doc {
+			tf[t]++
+		}
+
+		score := 0.0
+		for _, t := range terms {
+			f := float64(tf[t])
+			if f == 0 {
+				continue
+			}
+			idf := math.Log((n-float64(df[t])+0.5)/(float64(df[t])+0.5) + 1)
+			score += idf * f * (k1 + 1) / (f + k1*(1-b+b*float64(len(doc))/avgLen))
+		}
+		if score > 0 {
+			ranked = append(ranked, scored{i, score})
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+
+	var selected []Example
+	for _, r := range ranked {
+		if len(selected) == limit {
+			break
+		}
+		selected = append(selected, examples[r.idx])
+	}
+	return selected
+}
+
+// examplesLimit reads `examples_limit`; 0 disables examples
+func examplesLimit() int {
+	if !viper.IsSet("examples_limit") {
+		return DefaultExamplesLimit
+	}
+	return viper.GetInt("examples_limit")
+}
+
+// Words too common to say anything about relevance
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"by": true, "for": true, "from": true, "get": true, "give": true, "how": true,
+	"in": true, "is": true, "it": true, "me": true, "of": true, "on": true,
+	"or": true, "show": true, "the": true, "to": true, "what": true, "which": true,
+	"with": true, "all": true, "list": true, "find": true, "select": true,
+	"where": true, "per": true, "each": true,
+}
+
+// tokenize lowercases, splits on anything but letters and digits
+// (so snake_case identifiers match plain words), drops stop words and
+// strips a plural "s"
+func tokenize(s string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var terms []string
+	for _, f := range fields {
+		if len(f) < 2 || stopWords[f] {
+			continue
+		}
+		if len(f) > 3 && strings.HasSuffix(f, "s") && !strings.HasSuffix(f, "ss") {
+			f = strings.TrimSuffix(f, "s")
+		}
+		terms = append(terms, f)
+	}
+	return terms
+}
+
+// examplesAddition renders selected examples as a prompt section
+func examplesAddition(examples []Example) string {
+	var sb strings.Builder
+	sb.WriteString("\n\nVerified examples from this codebase. When a request matches one, follow its tables, joins and conventions:\n")
+	for _, ex := range examples {
+		sb.WriteString("\nQuestion: " + strings.TrimSpace(ex.Question) + "\n")
+		sb.WriteString("```sql\n" + strings.TrimSpace(ex.SQL) + "\n```\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,72 @@
+package prompt
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"Show me all the Orders", []string{"order"}},
+		{"paid_at, amount_cents", []string{"paid", "amount", "cent"}},
+		{"monthly revenue 2024", []string{"monthly", "revenue", "2024"}},
+		// The plural rule is naive: it pins "status" to "statu", which
+		// still matches as long as both sides go through tokenize
+		{"order status", []string{"order", "statu"}},
+		{"address class gas", []string{"address", "class", "gas"}},
+		{"a b x", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := tokenize(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSelectExamples(t *testing.T) {
+	examples := []Example{
+		{Question: "monthly revenue", SQL: "SELECT date_trunc('month', paid_at), sum(amount_cents) FROM payments GROUP BY 1"},
+		{Question: "active users", SQL: "SELECT * FROM users WHERE deleted_at IS NULL"},
+		{Question: "orders by status", SQL: "SELECT status, count(*) FROM orders GROUP BY status"},
+		{Question: "revenue by region", SQL: "SELECT region, sum(amount_cents) FROM payments JOIN users ON users.id = payments.user_id GROUP BY region"},
+		{Question: "refunded orders", SQL: "SELECT * FROM orders WHERE refunded_at IS NOT NULL"},
+	}
+	questions := func(exs []Example) []string {
+		var out []string
+		for _, ex := range exs {
+			out = append(out, ex.Question)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		limit int
+		want  []string
+	}{
+		{"best match first", "revenue per region last year", 3, []string{"revenue by region", "monthly revenue"}},
+		{"top-k cutoff", "orders with a status", 1, []string{"orders by status"}},
+		{"shorter match ranks higher", "orders", 3, []string{"refunded orders", "orders by status"}},
+		{"plurals match", "which order has status shipped", 3, []string{"orders by status", "refunded orders"}},
+		{"identifiers match words", "when were payments paid", 3, []string{"monthly revenue", "revenue by region"}},
+		{"no shared terms", "inventory levels", 3, nil},
+		{"only stop words", "show me all of the", 3, nil},
+		{"no limit", "orders", 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := questions(SelectExamples(examples, tt.query, tt.limit)); !slices.Equal(got, tt.want) {
+				t.Errorf("SelectExamples(%q, %d) = %q, want %q", tt.query, tt.limit, got, tt.want)
+			}
+		})
+	}
+
+	if got := SelectExamples(nil, "orders", 3); got != nil {
+		t.Errorf("SelectExamples(nil) = %v, want none", got)
+	}
+}
@@ -0,0 +1,58 @@
+package prompt
+
+import (
+	"strings"
+
+	"github.com/amansingh-afk/qry/internal/security"
+	"github.com/spf13/viper"
+)
+
+const explainTemplate = `You are reviewing SQL for someone who has to sign off on it. Using the codebase context (schemas, migrations, models), explain this {{dialect}}{{version}} query in business terms.
+
+Cover, briefly:
+- What it returns, in one or two sentences
+- Tables involved and what they represent in this codebase
+- Filters, and which rows they keep or drop
+- Joins: what each one matches on, and whether it can drop or duplicate rows
+- Pitfalls: NULL handling, fan-out, time zones, soft deletes, performance
+
+Write plain text with short sections. Don't rewrite the query unless it has a bug.
+
+` + "```sql\n{{query}}\n```"
+
+// BuildExplain builds a prompt asking the backend to explain sql.
+// flagged lists objects the security rules exclude, which the
+// explanation should call out.
+func BuildExplain(sql, dialect string, flagged []string) string {
+	version := viper.GetString("db_version")
+	if version != "" {
+		version = " " + version
+	}
+	result := strings.NewReplacer(
+		"{{dialect}}", dialect,
+		"{{version}}", version,
+		"{{query}}", strings.TrimSpace(sql),
+	).Replace(explainTemplate)
+
+	// Glossary terms that appear in the SQL, e.g. a column named mrr
+	if defs := MatchGlossary(sql); len(defs) > 0 {
+		result += glossaryAddition(defs)
+	}
+
+	if paths := viper.GetStringSlice("schema_paths"); len(paths) > 0 {
+		result += schemaPathsAddition(paths)
+	}
+
+	// The same rules BuildSQL sends, so exploring the codebase for
+	// context stays clear of excluded data
+	result += security.PromptAddition()
+
+	if len(flagged) > 0 {
+		result += "\n\nThese objects are excluded by this project's security rules. Explaining a query that uses them is allowed: say where the query uses them, and don't describe what they contain:\n"
+		for _, f := range flagged {
+			result += "- " + f + "\n"
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,41 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/amansingh-afk/qry/internal/security"
+	"github.com/spf13/viper"
+)
+
+// setExclusions excludes tables for the length of the test
+func setExclusions(t *testing.T, tables ...string) {
+	t.Helper()
+	viper.Set("security.mode", "strict")
+	viper.Set("security.exclude.tables", tables)
+	security.Reset()
+	t.Cleanup(func() {
+		viper.Set("security.mode", "")
+		viper.Set("security.exclude.tables", nil)
+		security.Reset()
+	})
+}
+
+// The explain prompt carries the same security rules as BuildSQL, so an
+// agent exploring the repo for context avoids excluded data too
+func TestExplainSecurityRules(t *testing.T) {
+	setExclusions(t, "api_keys")
+
+	rules := security.PromptAddition()
+	if !strings.Contains(rules, "api_keys") {
+		t.Fatalf("security rules = %q, want api_keys excluded", rules)
+	}
+
+	p := BuildExplain("SELECT * FROM api_keys", "postgresql", []string{"api_keys"})
+	if !strings.Contains(p, rules) {
+		t.Errorf("prompt lacks the security rules:\n%s", p)
+	}
+	if !strings.Contains(p, "Explaining a query that uses them is allowed") {
+		t.Errorf("prompt doesn't call out the flagged objects:\n%s", p)
+	}
+}
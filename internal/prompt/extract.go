@@ -0,0 +1,292 @@
+package prompt
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// OutcomeKind is what a reply turned out to be
+type OutcomeKind string
+
+const (
+	OutcomeSQL           OutcomeKind = "sql"
+	OutcomeRefusal       OutcomeKind = "refusal"       // The model declined, e.g. restricted data
+	OutcomeClarification OutcomeKind = "clarification" // The model asked a question instead
+	OutcomeNoSQL         OutcomeKind = "no_sql"        // Neither SQL nor a recognisable answer
+)
+
+// Outcome is a reply sorted into SQL, a refusal, a clarifying question
+// or no SQL at all
+type Outcome struct {
+	Kind       OutcomeKind
+	SQL        string   // Every statement, as written
+	Statements []string // SQL split into statements
+	Message    string   // The refusal, the question, or the text with no SQL
+	Options    []string // Suggested answers to a clarifying question
+}
+
+var sqlBlock = regexp.MustCompile("(?s)```sql\\s*(.+?)\\s*```")
+var anyBlock = regexp.MustCompile("(?s)```[a-zA-Z]*\\s*(.+?)\\s*```")
+
+// Statements have one of these shapes. A leading keyword isn't enough:
+// "With the tables excluded, ..." and "Show me ..." are English.
+var sqlShape = regexp.MustCompile(`(?is)^(` +
+	`select\s+.+?\bfrom\b|select\s+(\d|'|\*|-|[a-z_][\w.]*\s*\()|` +
+	"with\\s+(recursive\\s+)?[\\w\"`]+\\s*(\\([^)]*\\)\\s*)?as\\s+(not\\s+)?(materialized\\s+)?\\(|" +
+	`insert\s+(ignore\s+)?into\s|replace\s+into\s|update\s+\S+\s+(\S+\s+)?set\s|delete\s+from\s|merge\s+into\s|` +
+	`(create|alter|drop)\s+(or\s+replace\s+)?((temp|temporary|unique|materialized)\s+)?(table|view|index|function|procedure|schema|database|trigger|sequence|type|extension|role|user|policy)\s|` +
+	`truncate\s+(table\s+)?\S|(grant|revoke)\s.+?\s(on|to|from)\s|` +
+	`explain\s+((analyze|verbose|\([^)]*\))\s+)*(select|with|insert|update|delete)\b|` +
+	`(describe|desc)\s+\S+\s*;?\s*$|` +
+	`show\s+(tables|databases|schemas|columns|index|indexes|create|full|variables|status|grants|processlist|search_path|timezone)\b|` +
+	`pragma\s+\w|values\s*\(|call\s+[\w.]+\s*\(|copy\s+\S+.*?\s(from|to)\s|` +
+	`(analyze|vacuum)(\s+\S+)?\s*;?\s*$|set\s+((session|local)\s+)?[\w.]+\s*(=|to)\s)`)
+
+// A question in prose: a word followed by "?", unlike a placeholder (= ?)
+var proseQuestion = regexp.MustCompile(`(?m)[A-Za-z]\?(\s|$)`)
+
+// The refusal the security rules ask for (see security.PromptAddition)
+var securityRefusal = regexp.MustCompile(`(?i)^\W*cannot generate this query`)
+
+var refusalStart = regexp.MustCompile(`(?i)^\W*(i'?m sorry|sorry|unfortunately|i (cannot|can't|can not|won't|will not|must decline)|i'?m (unable|not able)|i am (unable|not able)|(cannot|can't|unable to) (generate|write|create|help|provide|answer))\b`)
+
+// A refusal later in the first sentence, e.g. "With those tables
+// excluded, I can't write this query."
+var refusalPhrase = regexp.MustCompile(`(?i)\b(i|we) (cannot|can't|can not|won't|will not|must decline|am unable|am not able|'m unable|'m not able)\b`)
+
+// Extract sorts a reply. Fenced ```sql blocks win, then any fenced
+// block. Unfenced text is a refusal or question if it reads as one, and
+// SQL only if it's shaped like a statement. Several blocks are all kept.
+func Extract(response string) Outcome {
+	text := strings.TrimSpace(response)
+
+	blocks := sqlBlock.FindAllStringSubmatch(text, -1)
+	if len(blocks) == 0 {
+		blocks = anyBlock.FindAllStringSubmatch(text, -1)
+	}
+	if len(blocks) > 0 {
+		return blocksOutcome(blocks)
+	}
+
+	// Refusals and questions before SQL after a lead-in, so a question
+	// ending in "options:" isn't read as SQL
+	switch {
+	case securityRefusal.MatchString(text), refusalStart.MatchString(text), clarifyMarker.MatchString(text):
+		return classify(text)
+	case isSQL(text):
+		return sqlOutcome(text)
+	case proseQuestion.MatchString(text):
+		return classify(text)
+	}
+	if sql := bareSQL(text); sql != "" {
+		return sqlOutcome(sql)
+	}
+
+	return classify(text)
+}
+
+// ExtractReply is Extract for a reply in either format. With
+// `structured: true` the "sql" field is extracted, and an empty one
+// means the explanation is the refusal or the question.
+func ExtractReply(response string) Outcome {
+	if Structured() {
+		start := strings.Index(response, "{")
+		end := strings.LastIndex(response, "}")
+		if start != -1 && end > start {
+			var a Answer
+			if json.Unmarshal([]byte(response[start:end+1]), &a) == nil {
+				if strings.TrimSpace(a.SQL) != "" {
+					return a.Outcome()
+				}
+				if c := a.Clarification; c != nil && strings.TrimSpace(c.Question) != "" {
+					return Outcome{Kind: OutcomeClarification, Message: strings.TrimSpace(c.Question), Options: c.Options}
+				}
+				if strings.TrimSpace(a.Explanation) != "" {
+					return classify(strings.TrimSpace(a.Explanation))
+				}
+			}
+		}
+	}
+	return Extract(response)
+}
+
+// Outcome sorts a structured answer's "sql" field. It's SQL by contract,
+// so it's kept even when it isn't shaped like a statement Extract knows.
+func (a Answer) Outcome() Outcome {
+	if out := Extract(a.SQL); out.Kind == OutcomeSQL {
+		return out
+	}
+	return sqlOutcome(strings.TrimSpace(a.SQL))
+}
+
+// ExtractSQL returns just the SQL in a reply, or "" if there is none
+func ExtractSQL(response string) string {
+	return Extract(response).SQL
+}
+
+func sqlOutcome(sql string) Outcome {
+	return Outcome{Kind: OutcomeSQL, SQL: sql, Statements: SplitStatements(sql)}
+}
+
+// blocksOutcome keeps every fenced block. Each is split on its own, so
+// blocks without a trailing semicolon stay separate statements, and one
+// is added when joining them.
+func blocksOutcome(blocks [][]string) Outcome {
+	out := Outcome{Kind: OutcomeSQL}
+	parts := make([]string, len(blocks))
+	for i, m := range blocks {
+		block := strings.TrimSpace(m[1])
+		out.Statements = append(out.Statements, SplitStatements(block)...)
+
+		if i < len(blocks)-1 && !strings.HasSuffix(block, ";") {
+			// A trailing -- comment would swallow the semicolon
+			if lines := strings.Split(block, "\n"); strings.Contains(lines[len(lines)-1], "--") {
+				block += "\n"
+			}
+			block += ";"
+		}
+		parts[i] = block
+	}
+	out.SQL = strings.Join(parts, "\n\n")
+	return out
+}
+
+// isSQL reports whether text is shaped like a statement, ignoring
+// leading comments and the parentheses of (SELECT ...) UNION (SELECT ...).
+// A full stop at the end makes it a sentence.
+func isSQL(text string) bool {
+	if strings.HasSuffix(strings.TrimSpace(text), ".") {
+		return false
+	}
+	return sqlShape.MatchString(strings.TrimLeft(stripComments(text), "( \t\n"))
+}
+
+// bareSQL returns the SQL after a lead-in such as "Here is the query:"
+func bareSQL(text string) string {
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		prev := strings.TrimSpace(strings.Join(lines[:i], " "))
+		rest := strings.TrimSpace(strings.Join(lines[i:], "\n"))
+		if strings.HasSuffix(prev, ":") && isSQL(rest) {
+			return rest
+		}
+	}
+	return ""
+}
+
+// classify sorts a reply with no SQL in it
+func classify(text string) Outcome {
+	switch {
+	case text == "":
+		return Outcome{Kind: OutcomeNoSQL}
+	case securityRefusal.MatchString(text):
+		return Outcome{Kind: OutcomeRefusal, Message: text}
+	case clarifyMarker.MatchString(text), asksQuestion(text):
+		c := parseClarification(text)
+		return Outcome{Kind: OutcomeClarification, Message: c.Question, Options: c.Options}
+	case refusalStart.MatchString(text), refusalPhrase.MatchString(firstSentence(text)):
+		return Outcome{Kind: OutcomeRefusal, Message: text}
+	default:
+		return Outcome{Kind: OutcomeNoSQL, Message: text}
+	}
+}
+
+// firstSentence returns text up to the first full stop or line break
+func firstSentence(text string) string {
+	if i := strings.IndexAny(text, ".\n"); i >= 0 {
+		return text[:i]
+	}
+	return text
+}
+
+// asksQuestion reports whether any sentence in text ends with "?"
+func asksQuestion(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasSuffix(line, "?") || strings.Contains(line, "? ") {
+			return true
+		}
+	}
+	return false
+}
+
+// stripComments drops leading -- and /* */ comments
+func stripComments(s string) string {
+	for {
+		s = strings.TrimSpace(s)
+		switch {
+		case strings.HasPrefix(s, "--"):
+			end := strings.Index(s, "\n")
+			if end == -1 {
+				return ""
+			}
+			s = s[end:]
+		case strings.HasPrefix(s, "/*"):
+			end := strings.Index(s, "*/")
+			if end == -1 {
+				return ""
+			}
+			s = s[end+2:]
+		default:
+			return s
+		}
+	}
+}
+
+// SplitStatements splits SQL on semicolons outside quotes, comments and
+// dollar-quoted bodies. Statements that are only comments are dropped.
+func SplitStatements(sql string) []string {
+	var (
+		statements []string
+		start      int
+	)
+	add := func(end int) {
+		stmt := strings.TrimSpace(sql[start:end])
+		if strings.TrimSuffix(stripComments(stmt), ";") != "" {
+			statements = append(statements, stmt)
+		}
+	}
+
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			// Quotes are escaped by doubling, which this loop also handles
+			if end := strings.IndexByte(sql[i+1:], c); end != -1 {
+				i += end + 1
+			} else {
+				i = len(sql)
+			}
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			if end := strings.IndexByte(sql[i:], '\n'); end != -1 {
+				i += end
+			} else {
+				i = len(sql)
+			}
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			if end := strings.Index(sql[i+2:], "*/"); end != -1 {
+				i += end + 3
+			} else {
+				i = len(sql)
+			}
+		case c == '$':
+			// $$ or $tag$ opens a body that runs to the same tag
+			if tag := dollarTag.FindString(sql[i:]); tag != "" {
+				if end := strings.Index(sql[i+len(tag):], tag); end != -1 {
+					i += len(tag) + end + len(tag) - 1
+				} else {
+					i = len(sql)
+				}
+			}
+		case c == ';':
+			add(i + 1)
+			start = i + 1
+		}
+	}
+	if start < len(sql) {
+		add(len(sql))
+	}
+	return statements
+}
+
+var dollarTag = regexp.MustCompile(`^\$[A-Za-z_]*\$`)
@@ -0,0 +1,116 @@
+package prompt
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name       string
+		response   string
+		kind       OutcomeKind
+		sql        string
+		statements []string
+	}{
+		{
+			name:       "fenced",
+			response:   "Here you go:\n```sql\nSELECT id FROM users;\n```",
+			kind:       OutcomeSQL,
+			sql:        "SELECT id FROM users;",
+			statements: []string{"SELECT id FROM users;"},
+		},
+		{
+			name:       "blocks without semicolons",
+			response:   "```sql\nSELECT 1\n```\nand\n```sql\nSELECT 2\n```",
+			kind:       OutcomeSQL,
+			sql:        "SELECT 1;\n\nSELECT 2",
+			statements: []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name:       "block ending in a comment",
+			response:   "```sql\nSELECT 1 -- first\n```\n```sql\nSELECT 2\n```",
+			kind:       OutcomeSQL,
+			sql:        "SELECT 1 -- first\n;\n\nSELECT 2",
+			statements: []string{"SELECT 1 -- first", "SELECT 2"},
+		},
+		{
+			name:       "bare",
+			response:   "SELECT * FROM orders WHERE id = ?",
+			kind:       OutcomeSQL,
+			sql:        "SELECT * FROM orders WHERE id = ?",
+			statements: []string{"SELECT * FROM orders WHERE id = ?"},
+		},
+		{
+			name:       "parenthesised union",
+			response:   "(SELECT a FROM t) UNION (SELECT a FROM u)",
+			kind:       OutcomeSQL,
+			sql:        "(SELECT a FROM t) UNION (SELECT a FROM u)",
+			statements: []string{"(SELECT a FROM t) UNION (SELECT a FROM u)"},
+		},
+		{
+			name:       "cte",
+			response:   "WITH recent AS (SELECT * FROM orders) SELECT count(*) FROM recent",
+			kind:       OutcomeSQL,
+			sql:        "WITH recent AS (SELECT * FROM orders) SELECT count(*) FROM recent",
+			statements: []string{"WITH recent AS (SELECT * FROM orders) SELECT count(*) FROM recent"},
+		},
+		{
+			name:       "lead-in",
+			response:   "Here is the query:\nSELECT id\nFROM users",
+			kind:       OutcomeSQL,
+			sql:        "SELECT id\nFROM users",
+			statements: []string{"SELECT id\nFROM users"},
+		},
+		{name: "security refusal", response: "Cannot generate this query: it needs api_keys.", kind: OutcomeRefusal},
+		{name: "refusal", response: "I'm sorry, I can't help with that.", kind: OutcomeRefusal},
+		{name: "refusal opening with with", response: "With the restricted tables excluded, I can't write this query.", kind: OutcomeRefusal},
+		{name: "question opening with show", response: "Show me which quarter you mean: fiscal or calendar?", kind: OutcomeClarification},
+		{name: "clarify marker", response: "CLARIFY: Gross or net?\n- Gross\n- Net", kind: OutcomeClarification},
+		{name: "sentence opening with select", response: "Select the rows from the orders table.", kind: OutcomeNoSQL},
+		{name: "prose", response: "The orders table has no created_at column.", kind: OutcomeNoSQL},
+		{name: "empty", response: "", kind: OutcomeNoSQL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := Extract(tt.response)
+			if out.Kind != tt.kind {
+				t.Fatalf("Kind = %s, want %s (message %q)", out.Kind, tt.kind, out.Message)
+			}
+			if out.SQL != tt.sql {
+				t.Errorf("SQL = %q, want %q", out.SQL, tt.sql)
+			}
+			if !slices.Equal(out.Statements, tt.statements) {
+				t.Errorf("Statements = %q, want %q", out.Statements, tt.statements)
+			}
+		})
+	}
+}
+
+func TestExtractClarificationOptions(t *testing.T) {
+	out := Extract("CLARIFY: Fiscal or calendar quarter?\n- Fiscal quarter\n- Calendar quarter")
+	if out.Message != "Fiscal or calendar quarter?" {
+		t.Errorf("Message = %q", out.Message)
+	}
+	if want := []string{"Fiscal quarter", "Calendar quarter"}; !slices.Equal(out.Options, want) {
+		t.Errorf("Options = %q, want %q", out.Options, want)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want []string
+	}{
+		{"SELECT 1; SELECT 2;", []string{"SELECT 1;", "SELECT 2;"}},
+		{"SELECT ';' AS semi", []string{"SELECT ';' AS semi"}},
+		{"SELECT 1; -- done", []string{"SELECT 1;"}},
+		{"CREATE FUNCTION f() AS $$ SELECT 1; $$ LANGUAGE sql; SELECT f()", []string{"CREATE FUNCTION f() AS $$ SELECT 1; $$ LANGUAGE sql;", "SELECT f()"}},
+	}
+	for _, tt := range tests {
+		if got := SplitStatements(tt.sql); !slices.Equal(got, tt.want) {
+			t.Errorf("SplitStatements(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package prompt
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// Definition is a business term and what it means in SQL terms:
+//
+//	glossary:
+//	  active user: last_seen_at > now() - interval '30 days' AND deleted_at IS NULL
+//	  mrr: sum(plan_price_cents) / 100.0 over subscriptions with status = 'active'
+type Definition struct {
+	Term       string `json:"term"`
+	Definition string `json:"definition"`
+}
+
+// MatchGlossary returns the `glossary:` entries the query mentions,
+// case-insensitively and allowing a plural ("active users")
+func MatchGlossary(query string) []Definition {
+	var matched []Definition
+	for term, def := range glossary() {
+		if termPattern(term).MatchString(query) {
+			matched = append(matched, Definition{Term: term, Definition: strings.TrimSpace(def)})
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool { return matched[i].Term < matched[j].Term })
+	return matched
+}
+
+// glossary reads `glossary:` from the config file as written. Viper
+// lowercases keys and splits them on dots, which would report "ARR" as
+// "arr" and turn "v2.0 plan" into nested keys. Without a file (or when
+// it can't be read) viper's view is used.
+func glossary() map[string]string {
+	if path := viper.ConfigFileUsed(); path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			var raw struct {
+				Glossary map[string]string `yaml:"glossary"`
+			}
+			if yaml.Unmarshal(data, &raw) == nil && raw.Glossary != nil {
+				return raw.Glossary
+			}
+		}
+	}
+	return viper.GetStringMapString("glossary")
+}
+
+// termPattern matches a term as whole words, with any whitespace
+// between them and an optional plural ending. The edges are checked
+// against the neighbouring characters rather than with \b, which never
+// matches after a term ending in punctuation ("C++", "Q4.").
+func termPattern(term string) *regexp.Regexp {
+	words := strings.Fields(regexp.QuoteMeta(term))
+	return regexp.MustCompile(`(?i)(^|\W)` + strings.Join(words, `\s+`) + `(s|es)?(\W|$)`)
+}
+
+// glossaryAddition renders matched definitions as a prompt section
+func glossaryAddition(defs []Definition) string {
+	var sb strings.Builder
+	sb.WriteString("\n\nDefinitions (use these exactly when the request mentions the term):\n")
+	for _, d := range defs {
+		sb.WriteString("- " + d.Term + ": " + d.Definition + "\n")
+	}
+	return sb.String()
+}
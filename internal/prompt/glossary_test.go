@@ -0,0 +1,70 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+const glossaryConfig = `glossary:
+  active user: last_seen_at > now() - interval '30 days'
+  ARR: sum(plan_price_cents) * 12 / 100.0
+  v2.0 plan: plan_version = 2
+  C++: language = 'cpp'
+  churn: cancelled_at IS NOT NULL
+`
+
+func TestMatchGlossary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".qry.yaml")
+	if err := os.WriteFile(path, []byte(glossaryConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	viper.SetConfigFile(path)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(viper.Reset)
+
+	tests := []struct {
+		query string
+		want  []string // Terms, sorted
+	}{
+		{"how many active users signed up", []string{"active user"}},
+		{"Active   User count", []string{"active user"}},
+		{"arr by region", []string{"ARR"}},
+		{"customers on the v2.0 plan", []string{"v2.0 plan"}},
+		{"v2 plans", nil},
+		{"developers who use C++ daily", []string{"C++"}},
+		{"which C++?", []string{"C++"}},
+		{"churned accounts", nil},
+		{"churn and ARR", []string{"ARR", "churn"}},
+		{"carry over", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, d := range MatchGlossary(tt.query) {
+			got = append(got, d.Term)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("MatchGlossary(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	defs := MatchGlossary("arr")
+	if len(defs) != 1 || defs[0].Definition != "sum(plan_price_cents) * 12 / 100.0" {
+		t.Errorf("definition = %+v", defs)
+	}
+}
+
+// Without a config file (flags, tests) the glossary comes from viper
+func TestMatchGlossaryWithoutFile(t *testing.T) {
+	viper.Set("glossary", map[string]string{"mrr": "monthly revenue"})
+	t.Cleanup(viper.Reset)
+
+	if defs := MatchGlossary("MRR last month"); len(defs) != 1 || defs[0].Term != "mrr" {
+		t.Errorf("MatchGlossary = %+v, want mrr", defs)
+	}
+}
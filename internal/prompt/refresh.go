@@ -0,0 +1,84 @@
+package prompt
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/amansingh-afk/qry/internal/security"
+	"github.com/spf13/viper"
+)
+
+var (
+	refreshMu sync.Mutex
+	lastStamp string
+
+	// configMu keeps viper, the security rules and the template from
+	// being reloaded while a request reads them (see ReadConfig)
+	configMu sync.RWMutex
+)
+
+// Refresh re-reads .qry.yaml, the security rules and the prompt template
+// when their files changed since the last call. Long-running commands
+// (serve, the TUI) call it before each request or turn, so RulesHash
+// sees edits made mid-session. The first call only records the state.
+//
+// The reload waits for requests holding ReadConfig to finish, since
+// viper's maps can't be read and written at once.
+func Refresh(workDir string) {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+
+	stamp := configStamp(workDir)
+	if lastStamp == "" || stamp == lastStamp {
+		lastStamp = stamp
+		return
+	}
+	lastStamp = stamp
+
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	_ = viper.ReadInConfig()
+	security.Reset()
+	_ = Load(workDir)
+}
+
+// ReadConfig holds the configuration steady until done is called. Code
+// that runs alongside Refresh (serve's handlers, the TUI's turns) reads
+// viper and the security rules only while holding it. It mustn't be
+// taken twice by the same goroutine.
+func ReadConfig() (done func()) {
+	configMu.RLock()
+	return configMu.RUnlock
+}
+
+// configStamp summarizes the size and modification time of the config
+// file and everything under .qry/prompts/
+func configStamp(workDir string) string {
+	var sb strings.Builder
+	add := func(path string) {
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&sb, "%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+
+	config := viper.ConfigFileUsed()
+	if config == "" {
+		config = filepath.Join(workDir, ".qry.yaml")
+	}
+	add(config)
+
+	_ = filepath.WalkDir(filepath.Join(workDir, PromptsDir), func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			add(path)
+		}
+		return nil
+	})
+
+	// Non-empty even with no files, so the first call is told apart
+	return "stamp;" + sb.String()
+}
@@ -1,11 +1,11 @@
 package prompt
 
 import (
-	"regexp"
+	"crypto/sha256"
+	"fmt"
 	"strings"
 
 	"github.com/amansingh-afk/qry/internal/security"
-	"github.com/spf13/viper"
 )
 
 const defaultPromptTemplate = `You are a SQL expert. Based on the codebase context (schemas, migrations, models), generate ONLY the SQL query.
@@ -20,51 +20,86 @@ Request: {{query}}`
 // BuildSQL builds the full prompt for the first query in a session.
 // This includes the role, rules, security rules, and the query.
 func BuildSQL(query string, dialect string) string {
-	// Get prompt template from config or use default
-	template := viper.GetString("prompt")
-	if template == "" {
-		template = defaultPromptTemplate
+	wd := loadDir()
+	data := newData(query, dialect, wd)
+
+	// Render the configured template (see template.go). Load already
+	// reported an invalid one, so fall back quietly.
+	t, _ := current()
+	result, err := render(t, data)
+	if err != nil {
+		data = newData(query, dialect, wd)
+		result, _ = render(defaultTemplate, data)
 	}
 
-	// Get db version from config
-	dbVersion := viper.GetString("db_version")
-	versionStr := ""
-	if dbVersion != "" {
-		versionStr = " " + dbVersion
+	// Spell out business terms the question uses
+	if len(data.definitions) > 0 && !data.usedDefinitions {
+		result += glossaryAddition(data.definitions)
 	}
 
-	// Replace template variables
-	result := template
-	result = strings.ReplaceAll(result, "{{query}}", query)
-	result = strings.ReplaceAll(result, "{{dialect}}", dialect)
-	result = strings.ReplaceAll(result, "{{version}}", versionStr)
+	// Pin canonical answers to similar questions
+	if len(data.examples) > 0 && !data.usedExamples {
+		result += examplesAddition(data.examples)
+	}
 
-	// Add security rules if configured
-	securityRules := security.PromptAddition()
-	if securityRules != "" {
-		result += securityRules
+	// Point the agent at the schema instead of letting it explore
+	if paths := data.schemaPaths; len(paths) > 0 && !data.usedSchemaPaths {
+		result += schemaPathsAddition(paths)
+	}
+
+	// Add security rules unless the template placed them
+	if data.security != "" && !data.usedSecurity {
+		result += data.security
+	}
+
+	// The answer format goes last so it wins over "output only the SQL".
+	// Both say how to ask when the request is ambiguous.
+	if Structured() {
+		result += answerInstructions
+	} else {
+		result += clarifyInstructions
 	}
 
 	return result
 }
 
-// BuildFollowUp builds a minimal prompt for subsequent queries in an existing session.
-// The LLM already knows its role from the first query.
-func BuildFollowUp(query string) string {
-	return query
+// schemaPathsAddition tells the agent where the schema is defined
+func schemaPathsAddition(paths []string) string {
+	var sb strings.Builder
+	sb.WriteString("\n\nThe database schema is defined in these paths (relative to the repository root):\n")
+	for _, p := range paths {
+		sb.WriteString("- " + p + "\n")
+	}
+	sb.WriteString("Read only these for table and column names. Do not explore the rest of the repository.")
+	return sb.String()
 }
 
-var sqlBlock = regexp.MustCompile("(?s)```sql\\s*(.+?)\\s*```")
-var anyBlock = regexp.MustCompile("(?s)```\\s*(.+?)\\s*```")
+// RulesHash identifies what a session is primed with: the prompt
+// template and its includes, the security rules and the answer format
+func RulesHash() string {
+	_, sum := current()
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%t", sum, security.PromptAddition(), Structured())
+	return fmt.Sprintf("%x", h.Sum(nil))[:16]
+}
 
-func ExtractSQL(response string) string {
-	if m := sqlBlock.FindStringSubmatch(response); len(m) > 1 {
-		return strings.TrimSpace(m[1])
-	}
+// BuildRefresh builds a follow-up for a session primed with other rules
+// (see RulesHash): the whole first-turn prompt, replacing the old one
+func BuildRefresh(query string, dialect string) string {
+	return "The instructions and security rules for this conversation have changed. " +
+		"These replace everything you were told before:\n\n" + BuildSQL(query, dialect)
+}
 
-	if m := anyBlock.FindStringSubmatch(response); len(m) > 1 {
-		return strings.TrimSpace(m[1])
+// BuildFollowUp builds a minimal prompt for subsequent queries in an existing session.
+// The LLM already knows its role from the first query, but glossary terms
+// are matched per question so they're added here too.
+func BuildFollowUp(query string) string {
+	result := query
+	if defs := MatchGlossary(query); len(defs) > 0 {
+		result += glossaryAddition(defs)
 	}
-
-	return strings.TrimSpace(response)
+	if Structured() {
+		result += answerReminder
+	}
+	return result
 }
@@ -0,0 +1,310 @@
+package prompt
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/amansingh-afk/qry/internal/security"
+	"github.com/spf13/viper"
+)
+
+// PromptsDir holds templates that the prompt can include by their path
+// relative to it: {{template "team/billing.md" .}}
+const PromptsDir = ".qry/prompts"
+
+// Data is what prompt templates render with:
+//
+//	{{.Query}} {{.Dialect}} {{.DBVersion}} {{.Date}} {{.TZ}} {{.Repo}}
+//	{{.Branch}} {{.Vars.team}} {{range .SchemaPaths}} {{range .Examples}}
+//	{{range .Definitions}} {{.Security}}
+//
+// The old {{query}}, {{dialect}} and {{version}} placeholders still work.
+type Data struct {
+	Query     string
+	Dialect   string
+	DBVersion string
+	Now       time.Time
+	Date      string            // 2006-01-02
+	TZ        string            // Zone abbreviation, e.g. CET
+	Vars      map[string]string // prompt_vars from .qry.yaml (keys lowercased)
+
+	workDir      string
+	repo, branch *string // Looked up on first use, see Repo and Branch
+
+	schemaPaths []string
+	examples    []Example
+	definitions []Definition
+	security    string
+
+	usedSchemaPaths bool
+	usedExamples    bool
+	usedDefinitions bool
+	usedSecurity    bool
+}
+
+// Version is DBVersion with a leading space, for "{{dialect}}{{version}}"
+func (d *Data) Version() string {
+	if d.DBVersion == "" {
+		return ""
+	}
+	return " " + d.DBVersion
+}
+
+// Repo returns the repository name. It runs git, so it's only looked up
+// when the template uses it.
+func (d *Data) Repo() string {
+	if d.repo == nil {
+		repo := ""
+		if d.workDir != "" {
+			repo = RepoName(d.workDir)
+		}
+		d.repo = &repo
+	}
+	return *d.repo
+}
+
+// Branch returns the current git branch, looked up like Repo
+func (d *Data) Branch() string {
+	if d.branch == nil {
+		branch := ""
+		if d.workDir != "" {
+			branch = gitBranch(d.workDir)
+		}
+		d.branch = &branch
+	}
+	return *d.branch
+}
+
+// SchemaPaths returns `schema_paths`. Using it in the template replaces
+// the block BuildSQL would otherwise append.
+func (d *Data) SchemaPaths() []string {
+	d.usedSchemaPaths = true
+	return d.schemaPaths
+}
+
+// Examples returns the examples selected for this query. Using it in the
+// template replaces the block BuildSQL would otherwise append.
+func (d *Data) Examples() []Example {
+	d.usedExamples = true
+	return d.examples
+}
+
+// Definitions returns the glossary entries the query mentions. Using it
+// in the template replaces the block BuildSQL would otherwise append.
+func (d *Data) Definitions() []Definition {
+	d.usedDefinitions = true
+	return d.definitions
+}
+
+// Security returns the security rules. Using it in the template places
+// them there instead of at the end.
+func (d *Data) Security() string {
+	d.usedSecurity = true
+	return strings.TrimSpace(d.security)
+}
+
+// Placeholders from before templates were real templates
+var legacyPlaceholders = strings.NewReplacer(
+	"{{query}}", "{{.Query}}",
+	"{{dialect}}", "{{.Dialect}}",
+	"{{version}}", "{{.Version}}",
+)
+
+var (
+	loadMu    sync.Mutex
+	loaded    *template.Template
+	loadedSum string // Hash of the template source, see RulesHash
+	loadedDir string // Work dir passed to Load
+	loadErr   error
+	isLoaded  bool
+)
+
+// defaultTemplate is parsed once; it has no includes
+var defaultTemplate = template.Must(newTemplate("prompt").Parse(legacyPlaceholders.Replace(defaultPromptTemplate)))
+
+func newTemplate(name string) *template.Template {
+	return template.New(name).Option("missingkey=zero")
+}
+
+// Load parses `prompt` and the templates under .qry/prompts/, and checks
+// the result renders and includes the query. Until it succeeds, BuildSQL
+// uses the default prompt.
+func Load(workDir string) error {
+	t, sum, err := parse(workDir, viper.GetString("prompt"))
+	if err == nil {
+		err = validate(t)
+	}
+
+	loadMu.Lock()
+	defer loadMu.Unlock()
+	loaded, loadedSum, loadedDir, loadErr, isLoaded = t, sum, workDir, err, true
+	return err
+}
+
+// loadDir returns the directory the template was loaded from, so building
+// a prompt doesn't look it up again
+func loadDir() string {
+	loadMu.Lock()
+	dir := loadedDir
+	loadMu.Unlock()
+
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	return dir
+}
+
+// current returns the loaded template and the hash of its source,
+// loading it on first use
+func current() (*template.Template, string) {
+	loadMu.Lock()
+	done := isLoaded
+	loadMu.Unlock()
+
+	if !done {
+		wd, _ := os.Getwd()
+		_ = Load(wd)
+	}
+
+	loadMu.Lock()
+	defer loadMu.Unlock()
+	if loadErr != nil || loaded == nil {
+		return defaultTemplate, defaultSum
+	}
+	return loaded, loadedSum
+}
+
+var defaultSum = fmt.Sprintf("%x", sha256.Sum256([]byte(defaultPromptTemplate)))
+
+// parse parses the prompt and its includes, and hashes their source
+func parse(workDir, text string) (*template.Template, string, error) {
+	if text == "" {
+		text = defaultPromptTemplate
+	}
+
+	t, err := newTemplate("prompt").Parse(legacyPlaceholders.Replace(text))
+	if err != nil {
+		return nil, "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(text))
+
+	dir := filepath.Join(workDir, PromptsDir)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir // No includes
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		fmt.Fprintf(h, "\x00%s\x00%s", rel, content)
+		if _, err := t.New(filepath.ToSlash(rel)).Parse(legacyPlaceholders.Replace(string(content))); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return t, fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// validate renders with sample data, so unknown fields and missing
+// includes fail at load time rather than on the first query
+func validate(t *template.Template) error {
+	const sample = "__qry_sample_query__"
+
+	data := newData(sample, "postgresql", "")
+	out, err := render(t, data)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(out, sample) {
+		return fmt.Errorf("prompt never includes the question; add {{.Query}}")
+	}
+	return nil
+}
+
+func newData(query, dialect, workDir string) *Data {
+	now := time.Now()
+	zone, _ := now.Zone()
+
+	d := &Data{
+		Query:       query,
+		Dialect:     dialect,
+		DBVersion:   viper.GetString("db_version"),
+		Now:         now,
+		Date:        now.Format("2006-01-02"),
+		TZ:          zone,
+		Vars:        viper.GetStringMapString("prompt_vars"),
+		schemaPaths: viper.GetStringSlice("schema_paths"),
+		definitions: MatchGlossary(query),
+		security:    security.PromptAddition(),
+		workDir:     workDir,
+	}
+	if workDir != "" {
+		// Bad examples are reported at startup; here they are skipped
+		if examples, err := LoadExamples(workDir); err == nil {
+			d.examples = SelectExamples(examples, query, examplesLimit())
+		}
+	}
+	return d
+}
+
+func render(t *template.Template, data *Data) (string, error) {
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// RepoName returns the repository name from the origin remote, or the
+// directory name
+func RepoName(workDir string) string {
+	cmd := exec.Command("git", "remote", "get-url", "origin")
+	cmd.Dir = workDir
+	if out, err := cmd.Output(); err == nil {
+		url := strings.TrimSpace(string(out))
+		// git@github.com:user/repo.git or https://github.com/user/repo.git
+		url = strings.TrimSuffix(url, ".git")
+		if idx := strings.LastIndex(url, "/"); idx != -1 {
+			return url[idx+1:]
+		}
+		if idx := strings.LastIndex(url, ":"); idx != -1 {
+			return url[idx+1:]
+		}
+	}
+
+	return filepath.Base(workDir)
+}
+
+// gitBranch returns the current branch, or "" outside a git repo
+func gitBranch(workDir string) string {
+	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
+	cmd.Dir = workDir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,33 @@
+package prompt
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRepoAndBranchOnlyWhenUsed(t *testing.T) {
+	dir := t.TempDir()
+
+	data := newData("active users", "postgresql", dir)
+	if _, err := render(defaultTemplate, data); err != nil {
+		t.Fatal(err)
+	}
+	if data.repo != nil || data.branch != nil {
+		t.Error("the default template looked up the repo or branch")
+	}
+
+	tmpl, _, err := parse(dir, "{{.Query}} in {{.Repo}} on [{{.Branch}}]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = newData("active users", "postgresql", dir)
+	out, err := render(tmpl, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Outside a git repo the name is the directory's and there's no branch
+	if want := "active users in " + filepath.Base(dir) + " on []"; out != want {
+		t.Errorf("rendered %q, want %q", out, want)
+	}
+}
@@ -0,0 +1,91 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/amansingh-afk/qry/internal/dialect"
+	"github.com/amansingh-afk/qry/internal/security"
+	"github.com/spf13/viper"
+)
+
+const translateTemplate = `Translate this {{from}} query to {{to}}. Using the codebase context (schemas, migrations, models), keep the same result: columns, names, row order, NULL handling and date arithmetic.
+
+Replace functions and syntax {{to}} doesn't have with their {{to}} equivalents. Don't change the tables, filters or joins, and don't add anything the original doesn't do.
+
+Reply with only the translated SQL in a single ` + "```sql" + ` block.
+
+` + "```sql\n{{query}}\n```"
+
+// BuildTranslate builds a prompt asking the backend to translate sql
+// from one dialect to another. Constructs the static check already
+// knows the target lacks are listed with their equivalents.
+func BuildTranslate(sql, from, to string) string {
+	result := strings.NewReplacer(
+		"{{from}}", from,
+		"{{to}}", to,
+		"{{query}}", strings.TrimSpace(sql),
+	).Replace(translateTemplate)
+
+	if paths := viper.GetStringSlice("schema_paths"); len(paths) > 0 {
+		result += schemaPathsAddition(paths)
+	}
+
+	// The same rules BuildSQL sends: a translation mustn't reach data a
+	// generated query couldn't
+	result += security.PromptAddition()
+
+	if issues := dialect.Check(sql, from, to); len(issues) > 0 {
+		result += "\n\n" + to + " doesn't support these, so replace them:\n" + issueList(issues)
+	}
+
+	return result
+}
+
+// ResolveTranslation extracts the translated SQL from a reply and, if it
+// still uses constructs the target lacks, gives the model one chance to
+// replace them. ask sends the repair prompt and returns the new reply.
+// Any constructs left are returned with the SQL.
+func ResolveTranslation(response, to string, ask func(repair string) (string, error)) (string, []dialect.Issue, error) {
+	sql, err := translatedSQL(response)
+	if err != nil {
+		return "", nil, err
+	}
+
+	issues := dialect.Check(sql, to, to)
+	if len(issues) == 0 {
+		return sql, nil, nil
+	}
+
+	repaired, askErr := ask(fmt.Sprintf("Your translation still uses constructs %s doesn't support:\n%s\n```sql\n%s\n```\n\nReply with the corrected SQL in a single ```sql block.",
+		to, issueList(issues), sql))
+	if askErr != nil {
+		return sql, issues, nil
+	}
+	fixed, err := translatedSQL(repaired)
+	if err != nil {
+		return sql, issues, nil
+	}
+	return fixed, dialect.Check(fixed, to, to), nil
+}
+
+// translatedSQL returns the SQL in a translate reply, or an error
+// carrying what the model said instead
+func translatedSQL(response string) (string, error) {
+	out := Extract(response)
+	if out.Kind == OutcomeSQL {
+		return out.SQL, nil
+	}
+	if out.Message == "" {
+		return "", fmt.Errorf("no SQL in the reply")
+	}
+	return "", fmt.Errorf("no SQL in the reply: %s", out.Message)
+}
+
+func issueList(issues []dialect.Issue) string {
+	var sb strings.Builder
+	for _, i := range issues {
+		sb.WriteString("- " + i.String() + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,17 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/amansingh-afk/qry/internal/security"
+)
+
+func TestTranslateSecurityRules(t *testing.T) {
+	setExclusions(t, "api_keys")
+
+	p := BuildTranslate("SELECT * FROM users", "mysql", "postgresql")
+	if rules := security.PromptAddition(); rules == "" || !strings.Contains(p, rules) {
+		t.Errorf("prompt lacks the security rules:\n%s", p)
+	}
+}
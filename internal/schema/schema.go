@@ -0,0 +1,261 @@
+// Package schema gathers schema-bearing files (migrations, models, DDL dumps)
+// from a repository, for backends that cannot explore the codebase themselves.
+package schema
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultBudget caps the amount of schema text sent with a prompt
+const DefaultBudget = 64 * 1024
+
+// File is a schema source found in the repository
+type File struct {
+	Path    string // Relative to the work directory
+	Content string
+}
+
+// Skipped directories (dependencies, build output, VCS)
+var skipDirs = map[string]bool{
+	".git": true, ".qry": true, "node_modules": true, "vendor": true,
+	"dist": true, "build": true, "target": true, ".venv": true,
+	"venv": true, "__pycache__": true, ".next": true, "tmp": true,
+}
+
+// Schema dumps describe the whole database in one file
+var dumpFiles = map[string]bool{
+	"schema.rb": true, "structure.sql": true, "schema.sql": true,
+	"schema.prisma": true,
+}
+
+var migrationDirs = map[string]bool{
+	"migrations": true, "migration": true, "migrate": true,
+}
+
+var modelDirs = map[string]bool{
+	"models": true, "model": true, "entities": true, "entity": true,
+}
+
+var codeExts = map[string]bool{
+	".sql": true, ".rb": true, ".py": true, ".go": true, ".ts": true,
+	".js": true, ".exs": true, ".ex": true, ".php": true, ".java": true,
+	".kt": true, ".cs": true, ".prisma": true,
+}
+
+// Per-file cap so one huge dump can't starve everything else
+const maxFileBytes = 32 * 1024
+
+// rank orders files: dumps first, then models, then migrations
+func rank(rel string) int {
+	base := filepath.Base(rel)
+	ext := filepath.Ext(base)
+
+	if dumpFiles[base] || ext == ".prisma" {
+		return 0
+	}
+
+	dirs := strings.Split(filepath.ToSlash(filepath.Dir(rel)), "/")
+	for _, d := range dirs {
+		if modelDirs[d] && codeExts[ext] {
+			return 1
+		}
+	}
+	for _, d := range dirs {
+		if migrationDirs[d] && codeExts[ext] {
+			return 2
+		}
+	}
+
+	if ext == ".sql" {
+		return 2
+	}
+
+	return -1
+}
+
+// Collect walks workDir and returns schema files, most informative first,
+// until maxBytes of content has been gathered
+func Collect(workDir string, maxBytes int) ([]File, error) {
+	type candidate struct {
+		rel  string
+		rank int
+	}
+
+	var candidates []candidate
+
+	err := filepath.WalkDir(workDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Unreadable entry, skip
+		}
+		if d.IsDir() {
+			if path != workDir && skipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(workDir, path)
+		if err != nil {
+			return nil
+		}
+
+		if r := rank(rel); r >= 0 {
+			candidates = append(candidates, candidate{rel: rel, rank: r})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].rank != candidates[j].rank {
+			return candidates[i].rank < candidates[j].rank
+		}
+		return candidates[i].rel < candidates[j].rel
+	})
+
+	paths := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		paths = append(paths, c.rel)
+	}
+
+	return read(workDir, paths, maxBytes), nil
+}
+
+// CollectPaths reads the given files and directories (relative to
+// workDir), most informative first, until maxBytes has been gathered.
+// Unlike Collect, every source file under a declared directory counts.
+func CollectPaths(workDir string, paths []string, maxBytes int) ([]File, error) {
+	type candidate struct {
+		rel  string
+		rank int
+	}
+
+	var candidates []candidate
+	seen := make(map[string]bool)
+
+	add := func(path string) {
+		rel, err := filepath.Rel(workDir, path)
+		if err != nil || seen[rel] {
+			return
+		}
+		seen[rel] = true
+
+		// Declared sources rank after dumps, models and migrations found in them
+		r := rank(rel)
+		if r < 0 {
+			r = 3
+		}
+		candidates = append(candidates, candidate{rel: rel, rank: r})
+	}
+
+	for _, p := range paths {
+		root := p
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(workDir, p)
+		}
+
+		info, err := os.Stat(root)
+		if err != nil {
+			continue // Missing paths are skipped, like unreadable entries
+		}
+		if !info.IsDir() {
+			add(root)
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != root && skipDirs[d.Name()] {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if codeExts[filepath.Ext(path)] || dumpFiles[d.Name()] {
+				add(path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].rank != candidates[j].rank {
+			return candidates[i].rank < candidates[j].rank
+		}
+		return candidates[i].rel < candidates[j].rel
+	})
+
+	rels := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		rels = append(rels, c.rel)
+	}
+
+	return read(workDir, rels, maxBytes), nil
+}
+
+// read loads files in order until the budget is spent
+func read(workDir string, paths []string, maxBytes int) []File {
+	if maxBytes <= 0 {
+		maxBytes = DefaultBudget
+	}
+
+	var files []File
+	used := 0
+
+	for _, rel := range paths {
+		if used >= maxBytes {
+			break
+		}
+
+		data, err := os.ReadFile(filepath.Join(workDir, rel))
+		if err != nil {
+			continue
+		}
+
+		content := string(data)
+		if len(content) > maxFileBytes {
+			content = content[:maxFileBytes] + "\n... (truncated)"
+		}
+		if used+len(content) > maxBytes {
+			content = content[:maxBytes-used] + "\n... (truncated)"
+		}
+
+		used += len(content)
+		files = append(files, File{Path: filepath.ToSlash(rel), Content: content})
+	}
+
+	return files
+}
+
+// Context renders collected files as a prompt section.
+// Returns empty string if no schema files were found.
+func Context(files []File) string {
+	if len(files) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Database schema sources from the repository:\n")
+	for _, f := range files {
+		sb.WriteString("\n--- ")
+		sb.WriteString(f.Path)
+		sb.WriteString(" ---\n")
+		sb.WriteString(f.Content)
+		if !strings.HasSuffix(f.Content, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String()
+}
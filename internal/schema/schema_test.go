@@ -0,0 +1,130 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeTree creates files (path → content) under a new temp dir
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for rel, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func paths(files []File) []string {
+	var out []string
+	for _, f := range files {
+		out = append(out, f.Path)
+	}
+	return out
+}
+
+func TestCollect(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"db/migrate/002_orders.rb":    "create_table :orders",
+		"db/migrate/001_users.rb":     "create_table :users",
+		"db/schema.rb":                "ActiveRecord::Schema.define",
+		"app/models/user.rb":          "class User",
+		"app/controllers/users.rb":    "class UsersController",
+		"reports/monthly.sql":         "SELECT 1",
+		"README.md":                   "# app",
+		"node_modules/pkg/schema.sql": "CREATE TABLE vendored",
+		".qry/session":                "{}",
+	})
+
+	files, err := Collect(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Dumps, then models, then migrations and loose SQL, by path
+	want := []string{
+		"db/schema.rb",
+		"app/models/user.rb",
+		"db/migrate/001_users.rb",
+		"db/migrate/002_orders.rb",
+		"reports/monthly.sql",
+	}
+	if got := paths(files); !slices.Equal(got, want) {
+		t.Errorf("paths = %q, want %q", got, want)
+	}
+	if files[0].Content != "ActiveRecord::Schema.define" {
+		t.Errorf("content = %q", files[0].Content)
+	}
+}
+
+func TestCollectBudget(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"schema.sql":         strings.Repeat("a", maxFileBytes+100),
+		"migrations/001.sql": strings.Repeat("b", 100),
+		"migrations/002.sql": strings.Repeat("c", 100),
+	})
+
+	// One huge file is cut to the per-file cap
+	files, err := Collect(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 || !strings.HasSuffix(files[0].Content, "... (truncated)") || len(files[0].Content) > maxFileBytes+20 {
+		t.Errorf("files = %q, want the dump truncated and all three kept", paths(files))
+	}
+
+	// The budget stops collection once it's spent
+	files, err = Collect(dir, 150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := paths(files); !slices.Equal(got, []string{"schema.sql"}) {
+		t.Errorf("paths = %q, want only the dump within budget", got)
+	}
+	if !strings.HasPrefix(files[0].Content, strings.Repeat("a", 150)) || !strings.HasSuffix(files[0].Content, "... (truncated)") {
+		t.Errorf("content = %q...", files[0].Content[:20])
+	}
+}
+
+func TestCollectPaths(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"services/billing/schema.sql":   "CREATE TABLE invoices",
+		"services/billing/queries.go":   "package billing",
+		"services/billing/notes.txt":    "not code",
+		"services/billing/vendor/x.sql": "vendored",
+		"shared/types.ts":               "export type Id = string",
+		"db/schema.sql":                 "outside the declared paths",
+	})
+
+	files, err := CollectPaths(dir, []string{"services/billing", "shared/types.ts", "missing"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every source file under a declared dir counts, ranked after dumps
+	want := []string{"services/billing/schema.sql", "services/billing/queries.go", "shared/types.ts"}
+	if got := paths(files); !slices.Equal(got, want) {
+		t.Errorf("paths = %q, want %q", got, want)
+	}
+}
+
+func TestContext(t *testing.T) {
+	if got := Context(nil); got != "" {
+		t.Errorf("Context(nil) = %q, want empty", got)
+	}
+
+	got := Context([]File{{Path: "db/schema.sql", Content: "CREATE TABLE users"}, {Path: "x.sql", Content: "SELECT 1\n"}})
+	want := "Database schema sources from the repository:\n\n--- db/schema.sql ---\nCREATE TABLE users\n\n--- x.sql ---\nSELECT 1\n"
+	if got != want {
+		t.Errorf("Context =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -10,25 +10,28 @@ type Security struct {
 
 var (
 	instance *Security
-	once     sync.Once
+	mu       sync.Mutex
 )
 
 // Get returns the singleton security instance
-// Loads config on first call
+// Loads config on first call, and again after Reset
 func Get() *Security {
-	once.Do(func() {
+	mu.Lock()
+	defer mu.Unlock()
+	if instance == nil {
 		cfg := LoadConfig()
 		instance = &Security{
 			config:    cfg,
 			validator: NewValidator(cfg),
 		}
-	})
+	}
 	return instance
 }
 
 // Reset clears the singleton (useful for testing or config reload)
 func Reset() {
-	once = sync.Once{}
+	mu.Lock()
+	defer mu.Unlock()
 	instance = nil
 }
 
@@ -87,6 +90,11 @@ func Validate(sql string) *Result {
 	return Get().Validate(sql)
 }
 
+// ScanText checks prose for excluded names
+func ScanText(text string) *Result {
+	return Get().validator.ScanText(text)
+}
+
 // PromptAddition returns the security rules to add to the prompt
 func PromptAddition() string {
 	return Get().GetPromptAddition()
@@ -1,6 +1,9 @@
 package security
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // Mode determines how violations are handled
 type Mode string
@@ -56,16 +59,50 @@ func (r *Result) Error() string {
 
 	msg := "Security violation: query references excluded data\n"
 	for _, v := range r.Violations {
-		msg += "  - " + string(v.Type) + ": " + v.Name
-		if v.Rule != "" && v.Rule != v.Name {
-			msg += " (matched rule: " + v.Rule + ")"
+		msg += "  - " + v.String() + "\n"
+	}
+	return msg
+}
+
+// String describes the violation, e.g. "table: api_keys (matched rule: api_*)"
+func (v Violation) String() string {
+	s := string(v.Type) + ": " + v.Name
+	if v.Rule != "" && v.Rule != v.Name {
+		s += " (matched rule: " + v.Rule + ")"
+	}
+	if v.Context != "" {
+		s += " " + v.Context
+	}
+	return s
+}
+
+// Merge adds o's violations for names r doesn't already report
+func (r *Result) Merge(o *Result) *Result {
+	merged := &Result{Valid: r.Valid && o.Valid, SQL: r.SQL}
+	merged.Violations = append(merged.Violations, r.Violations...)
+
+	for _, v := range o.Violations {
+		known := false
+		for _, w := range r.Violations {
+			if strings.EqualFold(v.Name, w.Name) {
+				known = true
+				break
+			}
 		}
-		if v.Context != "" {
-			msg += " " + v.Context
+		if !known {
+			merged.Violations = append(merged.Violations, v)
 		}
-		msg += "\n"
 	}
-	return msg
+	return merged
+}
+
+// Flags lists the violations as strings, or nil if there are none
+func (r *Result) Flags() []string {
+	var flags []string
+	for _, v := range r.Violations {
+		flags = append(flags, v.String())
+	}
+	return flags
 }
 
 // Summary returns a short summary of violations
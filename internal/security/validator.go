@@ -1,5 +1,10 @@
 package security
 
+import (
+	"regexp"
+	"strings"
+)
+
 // Validator checks SQL against security rules
 type Validator struct {
 	config  *Config
@@ -60,6 +65,38 @@ func (v *Validator) Validate(sql string) *Result {
 	return result
 }
 
+var identifier = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// ScanText checks prose, such as an explanation of a query, for excluded
+// names. Every word is matched against the rules, since there's no SQL to
+// say which are tables or columns.
+func (v *Validator) ScanText(text string) *Result {
+	result := &Result{Valid: true}
+	if v.config == nil || !v.config.Enabled {
+		return result
+	}
+
+	seen := make(map[string]bool)
+	for _, word := range identifier.FindAllString(text, -1) {
+		lower := strings.ToLower(word)
+		if seen[lower] {
+			continue
+		}
+		seen[lower] = true
+
+		if matched, rule, vType := v.matcher.MatchAny(word); matched {
+			result.Valid = false
+			result.Violations = append(result.Violations, Violation{
+				Type:    vType,
+				Name:    word,
+				Rule:    rule,
+				Context: "in the explanation",
+			})
+		}
+	}
+	return result
+}
+
 // IsBlocked returns true if the result should be blocked (strict mode + violations)
 func (v *Validator) IsBlocked(result *Result) bool {
 	if result.Valid {
@@ -0,0 +1,45 @@
+package security
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestScanText(t *testing.T) {
+	v := NewValidator(&Config{
+		Enabled: true,
+		Mode:    ModeWarn,
+		Exclude: ExcludeConfig{
+			Tables:   []string{"api_keys"},
+			Columns:  []string{"password_hash"},
+			Patterns: []string{"*_secret"},
+		},
+	})
+
+	sql := v.Validate("SELECT u.id FROM users u JOIN api_keys k ON k.user_id = u.id")
+	text := v.ScanText("Joins `api_keys` to users. The users.password_hash and " +
+		"client_secret columns aren't read, and API_KEYS is only used to filter.")
+
+	var names []string
+	for _, violation := range text.Violations {
+		names = append(names, violation.Name)
+	}
+	if want := []string{"api_keys", "password_hash", "client_secret"}; !slices.Equal(names, want) {
+		t.Errorf("ScanText names = %q, want %q", names, want)
+	}
+
+	// api_keys is already reported from the SQL, so only the rest are added
+	got := sql.Merge(text).Flags()
+	want := []string{
+		"table: api_keys JOIN clause",
+		"column: password_hash in the explanation",
+		"table: client_secret (matched rule: *_secret) in the explanation",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Flags = %q, want %q", got, want)
+	}
+
+	if r := NewValidator(nil).ScanText("api_keys"); !r.Valid {
+		t.Error("ScanText without rules reported violations")
+	}
+}
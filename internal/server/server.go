@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/amansingh-afk/qry/internal/backend"
+	"github.com/amansingh-afk/qry/internal/dialect"
+	"github.com/amansingh-afk/qry/internal/ensemble"
 	"github.com/amansingh-afk/qry/internal/guardrails"
 	"github.com/amansingh-afk/qry/internal/prompt"
 	"github.com/amansingh-afk/qry/internal/security"
@@ -16,28 +19,67 @@ import (
 )
 
 type QueryRequest struct {
-	Query     string `json:"query"`
-	Backend   string `json:"backend,omitempty"`
-	Model     string `json:"model,omitempty"`
-	Dialect   string `json:"dialect,omitempty"`
-	SessionID string `json:"session_id,omitempty"` // For multi-turn conversations
+	Query     string   `json:"query"`
+	Backend   string   `json:"backend,omitempty"`
+	Model     string   `json:"model,omitempty"`
+	Dialect   string   `json:"dialect,omitempty"`
+	SessionID string   `json:"session_id,omitempty"` // For multi-turn conversations
+	Ensemble  []string `json:"ensemble,omitempty"`   // Query these backends in parallel and compare
+
+	Clarification *ClarificationAnswer `json:"clarification,omitempty"` // Answer to a needs_clarification response
+}
+
+// ClarificationAnswer answers the question in a ClarificationResponse.
+// The request's query stays the original question.
+type ClarificationAnswer struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+// ClarificationResponse is returned instead of SQL when the backend
+// asked a question
+type ClarificationResponse struct {
+	NeedsClarification bool     `json:"needs_clarification"`
+	Question           string   `json:"question"`
+	Options            []string `json:"options,omitempty"`
+	Backend            string   `json:"backend"`
+	Model              string   `json:"model,omitempty"`
+	SessionID          string   `json:"session_id,omitempty"`
 }
 
 type QueryResponse struct {
-	SQL             string `json:"sql"`
-	Backend         string `json:"backend"`
-	Model           string `json:"model,omitempty"`
-	Dialect         string `json:"dialect,omitempty"`
-	Warning         string `json:"warning,omitempty"`
-	SecurityWarning string `json:"security_warning,omitempty"`
-	SessionID       string `json:"session_id,omitempty"` // For multi-turn conversations
+	SQL             string         `json:"sql"`
+	Statements      []string       `json:"statements,omitempty"` // Set when the SQL has more than one
+	Backend         string         `json:"backend"`              // Backend that actually answered
+	Model           string         `json:"model,omitempty"`
+	Dialect         string         `json:"dialect,omitempty"`
+	Warning         string         `json:"warning,omitempty"`
+	SecurityWarning string         `json:"security_warning,omitempty"`
+	SessionID       string         `json:"session_id,omitempty"`    // For multi-turn conversations
+	FallbackFrom    []string       `json:"fallback_from,omitempty"` // Backends that failed first
+	Usage           *backend.Usage `json:"usage,omitempty"`
+
+	DefinitionsApplied []prompt.Definition `json:"definitions_applied,omitempty"` // Glossary terms the question used
+
+	// Set with `structured: true`
+	Explanation string   `json:"explanation,omitempty"`
+	Assumptions []string `json:"assumptions,omitempty"`
+	TablesUsed  []string `json:"tables_used,omitempty"`
+	Confidence  *float64 `json:"confidence,omitempty"`
 }
 
 type ErrorResponse struct {
 	Error string `json:"error"`
+	Kind  string `json:"kind,omitempty"` // Backend error classification, or why the reply had no SQL
 }
 
+// Start serves the API on port
 func Start(port int, workDir string) error {
+	return http.ListenAndServe(fmt.Sprintf(":%d", port), Handler(workDir))
+}
+
+// Handler serves the API for the repo at workDir
+func Handler(workDir string) http.Handler {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
@@ -45,19 +87,23 @@ func Start(port int, workDir string) error {
 		_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 	})
 
-	mux.HandleFunc("POST /query", func(w http.ResponseWriter, r *http.Request) {
-		handleQuery(w, r, workDir)
-	})
-
-	mux.HandleFunc("GET /session", func(w http.ResponseWriter, r *http.Request) {
-		handleGetSession(w, r, workDir)
-	})
+	mux.HandleFunc("POST /query", withConfig(workDir, handleQuery))
+	mux.HandleFunc("POST /explain", withConfig(workDir, handleExplain))
+	mux.HandleFunc("POST /translate", withConfig(workDir, handleTranslate))
+	mux.HandleFunc("GET /session", withConfig(workDir, handleGetSession))
+	mux.HandleFunc("DELETE /session", withConfig(workDir, handleDeleteSession))
 
-	mux.HandleFunc("DELETE /session", func(w http.ResponseWriter, r *http.Request) {
-		handleDeleteSession(w, r, workDir)
-	})
+	return mux
+}
 
-	return http.ListenAndServe(fmt.Sprintf(":%d", port), mux)
+// withConfig picks up .qry.yaml and template edits made while serving,
+// then holds the config steady while h runs: requests read it concurrently
+func withConfig(workDir string, h func(http.ResponseWriter, *http.Request, string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		prompt.Refresh(workDir)
+		defer prompt.ReadConfig()()
+		h(w, r, workDir)
+	}
 }
 
 // getSessionTTL parses the session TTL from config
@@ -99,77 +145,140 @@ func handleQuery(w http.ResponseWriter, r *http.Request, workDir string) {
 		return
 	}
 
-	backendName := req.Backend
-	if backendName == "" {
-		backendName = viper.GetString("backend")
+	// An answer to a clarifying question is folded into the original request
+	if c := req.Clarification; c != nil && strings.TrimSpace(c.Answer) != "" {
+		req.Query = prompt.ClarifiedQuery(req.Query, c.Question, c.Answer)
 	}
 
-	b, err := backend.Get(backendName)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+	if len(req.Ensemble) > 0 {
+		handleEnsemble(w, r, req, workDir)
 		return
 	}
 
-	if !b.Available() {
+	chain, err := requestChain(r.Context(), req.Backend, req.Model)
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: fmt.Sprintf("%s not available", b.Name())})
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	// Get model: request > config model > config defaults
-	model := req.Model
-	if model == "" {
-		model = viper.GetString("model")
-	}
-	if model == "" {
-		model = viper.GetString("defaults." + backendName)
-	}
-
 	dialect := req.Dialect
 	if dialect == "" {
 		dialect = viper.GetString("dialect")
 	}
 
-	// Server-side session management: use stored session if client didn't provide one
-	sessionID := req.SessionID
-	if sessionID == "" {
-		if s, _ := session.GetOrCreate(workDir, backendName, getSessionTTL()); s != nil {
-			sessionID = s.SessionID
+	var (
+		model        string
+		sessionID    string
+		fallbackFrom []string
+		sent         string
+		sentOpts     backend.Options
+	)
+
+	attempt := func(ctx context.Context, b backend.Backend) (backend.Result, error) {
+		model = requestModel(b, chain, req.Model)
+
+		// Server-side session management: use stored session if client
+		// didn't provide one. Fallbacks run statelessly, since looking
+		// one up would drop the primary's session.
+		sessionID = ""
+		if b == chain[0] {
+			sessionID = req.SessionID
+			if sessionID == "" {
+				if s, _ := session.GetOrCreate(workDir, b.Name(), getSessionTTL()); s != nil {
+					sessionID = s.SessionID
+				}
+			}
 		}
-	}
 
-	opts := backend.Options{
-		Model:     model,
-		Dialect:   dialect,
-		SessionID: sessionID,
-	}
+		opts := backend.Options{
+			Model:     model,
+			Dialect:   dialect,
+			SessionID: sessionID,
+		}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
-	defer cancel()
+		// Use full prompt for new sessions, minimal prompt for existing
+		// sessions, and the full prompt again if the rules changed since
+		switch {
+		case sessionID == "":
+			sent = prompt.BuildSQL(req.Query, dialect)
+		case session.RulesChanged(workDir, sessionID, prompt.RulesHash()):
+			sent = prompt.BuildRefresh(req.Query, dialect)
+		default:
+			sent = prompt.BuildFollowUp(req.Query)
+		}
 
-	// Use full prompt for new sessions, minimal prompt for existing sessions
-	var sqlPrompt string
-	if sessionID == "" {
-		sqlPrompt = prompt.BuildSQL(req.Query, dialect)
-	} else {
-		sqlPrompt = prompt.BuildFollowUp(req.Query)
+		sentOpts = opts
+		return b.Query(ctx, sent, workDir, opts)
 	}
 
-	result, err := b.Query(ctx, sqlPrompt, workDir, opts)
+	onFail := func(f backend.Failure) {
+		if !f.Retrying {
+			fallbackFrom = append(fallbackFrom, f.Backend)
+		}
+	}
 
+	b, result, err := backend.Fallback(r.Context(), chain, retry(), attempt, onFail)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+		kind := backend.KindOf(err)
+		w.WriteHeader(errorStatus(kind))
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error(), Kind: string(kind)})
 		return
 	}
 
-	// Persist session for future requests
+	out := prompt.ExtractReply(result.Response)
+
+	// Structured answers get one repair retry, in the same session if any.
+	// A refusal or a question is a valid answer and isn't repaired.
+	var (
+		answer      *prompt.Answer
+		answerError string
+	)
+	if prompt.Structured() && out.Kind != prompt.OutcomeRefusal && out.Kind != prompt.OutcomeClarification {
+		ask := backend.Repair(r.Context(), b, sent, workDir, sentOpts, backend.TimeoutFor(b.Name()), &result)
+		if a, err := prompt.ResolveAnswer(result.Response, ask); err != nil {
+			answerError = "invalid structured answer, using the raw reply: " + err.Error()
+		} else {
+			answer, out = &a, a.Outcome()
+		}
+	}
+
+	// Persist session for future requests; usage counts without one too.
+	// A fallback's session would replace the primary's, so it's dropped.
+	if b != chain[0] {
+		result.SessionID = ""
+	}
 	if result.SessionID != "" {
-		_ = session.Update(workDir, backendName, result.SessionID)
+		_ = session.Update(workDir, b.Name(), result.SessionID, prompt.RulesHash())
+	}
+	if u := result.Usage; !u.IsZero() {
+		_ = session.AddUsage(workDir, b.Name(), u.InputTokens, u.OutputTokens, u.CostUSD)
+	}
+
+	// A reply without SQL gets its own status so clients can branch on it
+	if out.Kind == prompt.OutcomeClarification {
+		w.WriteHeader(outcomeStatus(out.Kind))
+		_ = json.NewEncoder(w).Encode(ClarificationResponse{
+			NeedsClarification: true,
+			Question:           out.Message,
+			Options:            out.Options,
+			Backend:            b.Name(),
+			Model:              model,
+			SessionID:          result.SessionID,
+		})
+		return
+	}
+	if out.Kind != prompt.OutcomeSQL {
+		w.WriteHeader(outcomeStatus(out.Kind))
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: out.Message, Kind: string(out.Kind)})
+		return
 	}
+	sql := out.SQL
 
-	sql := prompt.ExtractSQL(result.Response)
+	var usage *backend.Usage
+	if !result.Usage.IsZero() {
+		usage = &result.Usage
+	}
 
 	// Security validation
 	secResult := security.Validate(sql)
@@ -189,8 +298,11 @@ func handleQuery(w http.ResponseWriter, r *http.Request, workDir string) {
 	}
 
 	warning := guardrails.Check(sql)
+	if answerError != "" {
+		warning = strings.TrimSpace(warning + "\n" + answerError)
+	}
 
-	_ = json.NewEncoder(w).Encode(QueryResponse{
+	resp := QueryResponse{
 		SQL:             sql,
 		Backend:         b.Name(),
 		Model:           model,
@@ -198,15 +310,474 @@ func handleQuery(w http.ResponseWriter, r *http.Request, workDir string) {
 		Warning:         warning,
 		SecurityWarning: securityWarning,
 		SessionID:       result.SessionID,
+		FallbackFrom:    fallbackFrom,
+		Usage:           usage,
+
+		DefinitionsApplied: prompt.MatchGlossary(req.Query),
+	}
+	if len(out.Statements) > 1 {
+		resp.Statements = out.Statements
+	}
+	if answer != nil {
+		resp.Explanation = answer.Explanation
+		resp.Assumptions = answer.Assumptions
+		resp.TablesUsed = answer.TablesUsed
+		resp.Confidence = &answer.Confidence
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// requestChain returns the backends to try: the request's backend alone,
+// or the configured backend and its fallbacks. A model the backend
+// doesn't list is rejected.
+func requestChain(ctx context.Context, name, model string) ([]backend.Backend, error) {
+	// Explicit backend in the request disables fallback
+	names := []string{name}
+	if name == "" {
+		names = append([]string{viper.GetString("backend")}, viper.GetStringSlice("fallback")...)
+	}
+
+	var chain []backend.Backend
+	for _, name := range names {
+		b, err := backend.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, b)
+	}
+
+	if len(chain) == 1 && !chain[0].Available() {
+		return nil, fmt.Errorf("%s not available", chain[0].Name())
+	}
+
+	if model != "" {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		models, err := backend.ListModels(ctx, chain[0])
+		cancel()
+		if err == nil && len(models) > 0 && !backend.MatchModel(models, model) {
+			return nil, fmt.Errorf("unknown model %q for %s (available: %s)", model, chain[0].Name(), strings.Join(models, ", "))
+		}
+	}
+
+	return chain, nil
+}
+
+// requestModel picks b's model: request > backend block > config model >
+// config defaults. Fallbacks use their own model.
+func requestModel(b backend.Backend, chain []backend.Backend, requested string) string {
+	model := ""
+	if b == chain[0] {
+		model = requested
+		if model == "" {
+			model = backend.ConfigFor(b.Name()).Model
+		}
+		if model == "" {
+			model = viper.GetString("model")
+		}
+	}
+	if model == "" {
+		model = backend.ModelFor(b.Name())
+	}
+	return model
+}
+
+// retry reads the retry settings
+func retry() backend.Retry {
+	return backend.Retry{
+		Attempts: viper.GetInt("retry.attempts"),
+		Backoff:  viper.GetDuration("retry.backoff"),
+		Timeout: func(b backend.Backend) time.Duration {
+			return backend.TimeoutFor(b.Name())
+		},
+	}
+}
+
+// errorStatus maps a backend error classification to an HTTP status
+func errorStatus(kind backend.ErrorKind) int {
+	switch kind {
+	case backend.KindNotInstalled:
+		return http.StatusBadRequest
+	case backend.KindRateLimit:
+		return http.StatusServiceUnavailable
+	case backend.KindTimeout:
+		return http.StatusGatewayTimeout
+	case backend.KindAuth, backend.KindMalformed:
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// outcomeStatus maps a reply that isn't SQL to an HTTP status
+func outcomeStatus(kind prompt.OutcomeKind) int {
+	switch kind {
+	case prompt.OutcomeRefusal:
+		return http.StatusForbidden
+	case prompt.OutcomeClarification:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// EnsembleResponse compares SQL from several backends
+type EnsembleResponse struct {
+	ensemble.Report
+	Dialect  string   `json:"dialect,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func handleEnsemble(w http.ResponseWriter, r *http.Request, req QueryRequest, workDir string) {
+	// Each backend uses its own default; one model can't fit them all
+	if req.Model != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "model doesn't apply to ensemble; set backends.<name>.model instead"})
+		return
+	}
+
+	// Like the CLI, skip backends that aren't installed and compare the rest
+	var (
+		backends []backend.Backend
+		warnings []string
+	)
+	for _, name := range req.Ensemble {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		b, err := backend.Get(name)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+			return
+		}
+		if !b.Available() {
+			warnings = append(warnings, fmt.Sprintf("%s not available, skipped", b.Name()))
+			continue
+		}
+		backends = append(backends, b)
+	}
+
+	if len(backends) < 2 {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "ensemble needs at least two available backends"})
+		return
+	}
+
+	dialect := req.Dialect
+	if dialect == "" {
+		dialect = viper.GetString("dialect")
+	}
+
+	// Fresh first-turn prompt: answers stay independent of the shared session
+	sqlPrompt := prompt.BuildSQL(req.Query, dialect)
+	sec := security.Get()
+
+	candidates := ensemble.Run(r.Context(), backends, func(ctx context.Context, b backend.Backend) (string, string, error) {
+		ctx, cancel := context.WithTimeout(ctx, backend.TimeoutFor(b.Name()))
+		defer cancel()
+
+		model := backend.ModelFor(b.Name())
+
+		result, err := b.Query(ctx, sqlPrompt, workDir, backend.Options{
+			Model:   model,
+			Dialect: dialect,
+		})
+		if err != nil {
+			return "", model, err
+		}
+
+		out := prompt.ExtractReply(result.Response)
+		if out.Kind != prompt.OutcomeSQL {
+			return "", model, fmt.Errorf("%s: %s", out.Kind, strings.SplitN(out.Message, "\n", 2)[0])
+		}
+		sql := out.SQL
+
+		if secResult := security.Validate(sql); sec.IsBlocked(secResult) {
+			return "", model, fmt.Errorf("security violation: %s", secResult.Summary())
+		}
+
+		return sql, model, nil
+	})
+
+	report := ensemble.Compare(candidates)
+
+	if report.Succeeded == 1 {
+		warnings = append(warnings, "only one backend returned SQL; nothing to compare")
+	}
+	for _, g := range report.Groups {
+		if secResult := security.Validate(g.SQL); sec.ShouldWarn(secResult) {
+			warnings = append(warnings, secResult.Error())
+		}
+		if warning := guardrails.Check(g.SQL); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	if len(report.Groups) == 0 {
+		w.WriteHeader(http.StatusBadGateway)
+	}
+
+	_ = json.NewEncoder(w).Encode(EnsembleResponse{
+		Report:   report,
+		Dialect:  dialect,
+		Warnings: warnings,
+	})
+}
+
+// ExplainRequest asks for SQL to be explained in business terms
+type ExplainRequest struct {
+	SQL     string `json:"sql"`
+	Backend string `json:"backend,omitempty"`
+	Model   string `json:"model,omitempty"`
+	Dialect string `json:"dialect,omitempty"`
+}
+
+// ExplainResponse is the explanation, with any excluded objects the SQL uses
+type ExplainResponse struct {
+	Explanation   string         `json:"explanation"`
+	Backend       string         `json:"backend"`
+	Model         string         `json:"model,omitempty"`
+	Dialect       string         `json:"dialect,omitempty"`
+	SecurityFlags []string       `json:"security_flags,omitempty"`
+	FallbackFrom  []string       `json:"fallback_from,omitempty"`
+	Usage         *backend.Usage `json:"usage,omitempty"`
+}
+
+// handleExplain explains SQL outside the shared session, so the
+// session's SQL-only instructions aren't muddied
+func handleExplain(w http.ResponseWriter, r *http.Request, workDir string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req ExplainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "invalid JSON"})
+		return
+	}
+
+	if strings.TrimSpace(req.SQL) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "sql required"})
+		return
+	}
+
+	chain, err := requestChain(r.Context(), req.Backend, req.Model)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	dialect := req.Dialect
+	if dialect == "" {
+		dialect = viper.GetString("dialect")
+	}
+
+	// Excluded objects are flagged whatever the mode: the SQL already exists
+	secResult := security.Validate(req.SQL)
+	explainPrompt := prompt.BuildExplain(req.SQL, dialect, secResult.Flags())
+
+	var (
+		model        string
+		fallbackFrom []string
+	)
+
+	attempt := func(ctx context.Context, b backend.Backend) (backend.Result, error) {
+		model = requestModel(b, chain, req.Model)
+		return b.Query(ctx, explainPrompt, workDir, backend.Options{Model: model, Dialect: dialect})
+	}
+
+	onFail := func(f backend.Failure) {
+		if !f.Retrying {
+			fallbackFrom = append(fallbackFrom, f.Backend)
+		}
+	}
+
+	b, result, err := backend.Fallback(r.Context(), chain, retry(), attempt, onFail)
+	if err != nil {
+		kind := backend.KindOf(err)
+		w.WriteHeader(errorStatus(kind))
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error(), Kind: string(kind)})
+		return
+	}
+
+	var usage *backend.Usage
+	if !result.Usage.IsZero() {
+		usage = &result.Usage
+	}
+
+	// The explanation can name excluded objects the SQL only implies
+	flags := secResult.Merge(security.ScanText(result.Response)).Flags()
+
+	_ = json.NewEncoder(w).Encode(ExplainResponse{
+		Explanation:   strings.TrimSpace(result.Response),
+		Backend:       b.Name(),
+		Model:         model,
+		Dialect:       dialect,
+		SecurityFlags: flags,
+		FallbackFrom:  fallbackFrom,
+		Usage:         usage,
+	})
+}
+
+// TranslateRequest asks for SQL to be translated to another dialect
+type TranslateRequest struct {
+	SQL     string `json:"sql"`
+	From    string `json:"from,omitempty"` // Defaults to the configured dialect
+	To      string `json:"to"`
+	Backend string `json:"backend,omitempty"`
+	Model   string `json:"model,omitempty"`
+}
+
+// TranslateResponse is the translated SQL, with any constructs the
+// target still doesn't support
+type TranslateResponse struct {
+	SQL          string          `json:"sql"`
+	From         string          `json:"from"`
+	To           string          `json:"to"`
+	Backend      string          `json:"backend"`
+	Model        string          `json:"model,omitempty"`
+	Issues       []dialect.Issue `json:"issues,omitempty"`
+	FallbackFrom []string        `json:"fallback_from,omitempty"`
+	Usage        *backend.Usage  `json:"usage,omitempty"`
+
+	Warning         string `json:"warning,omitempty"`
+	SecurityWarning string `json:"security_warning,omitempty"`
+}
+
+// handleTranslate translates SQL outside the shared session. A reply
+// that still uses constructs the target lacks gets one repair retry.
+func handleTranslate(w http.ResponseWriter, r *http.Request, workDir string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req TranslateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "invalid JSON"})
+		return
+	}
+
+	if strings.TrimSpace(req.SQL) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "sql required"})
+		return
+	}
+
+	if req.From == "" {
+		req.From = viper.GetString("dialect")
+	}
+	from, err := dialect.Normalize(req.From)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "from: " + err.Error()})
+		return
+	}
+	if req.To == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "to required"})
+		return
+	}
+	to, err := dialect.Normalize(req.To)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "to: " + err.Error()})
+		return
+	}
+	if from == to {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "from and to are both " + to})
+		return
+	}
+
+	chain, err := requestChain(r.Context(), req.Backend, req.Model)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	translatePrompt := prompt.BuildTranslate(req.SQL, from, to)
+
+	var (
+		model        string
+		fallbackFrom []string
+	)
+
+	attempt := func(ctx context.Context, b backend.Backend) (backend.Result, error) {
+		model = requestModel(b, chain, req.Model)
+		return b.Query(ctx, translatePrompt, workDir, backend.Options{Model: model, Dialect: to})
+	}
+
+	onFail := func(f backend.Failure) {
+		if !f.Retrying {
+			fallbackFrom = append(fallbackFrom, f.Backend)
+		}
+	}
+
+	b, result, err := backend.Fallback(r.Context(), chain, retry(), attempt, onFail)
+	if err != nil {
+		kind := backend.KindOf(err)
+		w.WriteHeader(errorStatus(kind))
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error(), Kind: string(kind)})
+		return
+	}
+
+	// There's no session, so the repair repeats the original prompt
+	ask := backend.Repair(r.Context(), b, translatePrompt, workDir, backend.Options{Model: model, Dialect: to}, backend.TimeoutFor(b.Name()), &result)
+	sql, issues, err := prompt.ResolveTranslation(result.Response, to, ask)
+	if err != nil {
+		w.WriteHeader(outcomeStatus(prompt.OutcomeNoSQL))
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error(), Kind: string(prompt.OutcomeNoSQL)})
+		return
+	}
+
+	var usage *backend.Usage
+	if !result.Usage.IsZero() {
+		usage = &result.Usage
+	}
+
+	// The translation is new SQL, so it's checked like any other
+	secResult := security.Validate(sql)
+	sec := security.Get()
+
+	if sec.IsBlocked(secResult) {
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{
+			Error: "Security violation: " + secResult.Summary(),
+		})
+		return
+	}
+
+	var securityWarning string
+	if sec.ShouldWarn(secResult) {
+		securityWarning = secResult.Error()
+	}
+
+	_ = json.NewEncoder(w).Encode(TranslateResponse{
+		SQL:          sql,
+		From:         from,
+		To:           to,
+		Backend:      b.Name(),
+		Model:        model,
+		Issues:       issues,
+		FallbackFrom: fallbackFrom,
+		Usage:        usage,
+
+		Warning:         guardrails.Check(sql),
+		SecurityWarning: securityWarning,
 	})
 }
 
 // SessionResponse represents session info
 type SessionResponse struct {
-	Backend   string `json:"backend"`
-	SessionID string `json:"session_id"`
-	CreatedAt string `json:"created_at"`
-	Age       string `json:"age"`
+	Backend   string        `json:"backend"`
+	SessionID string        `json:"session_id"`
+	CreatedAt string        `json:"created_at"`
+	Age       string        `json:"age"`
+	Usage     session.Usage `json:"usage"` // Totals across the session's queries
+
+	Totals map[string]session.Usage `json:"totals,omitempty"` // Per backend, across sessions
 }
 
 func handleGetSession(w http.ResponseWriter, r *http.Request, workDir string) {
@@ -219,6 +790,12 @@ func handleGetSession(w http.ResponseWriter, r *http.Request, workDir string) {
 		return
 	}
 
+	// Only stateless backends have run: there are totals but no session
+	if s.SessionID == "" {
+		_ = json.NewEncoder(w).Encode(SessionResponse{Totals: s.Totals})
+		return
+	}
+
 	age := time.Since(s.CreatedAt).Round(time.Minute)
 
 	_ = json.NewEncoder(w).Encode(SessionResponse{
@@ -226,6 +803,8 @@ func handleGetSession(w http.ResponseWriter, r *http.Request, workDir string) {
 		SessionID: s.SessionID,
 		CreatedAt: s.CreatedAt.Format(time.RFC3339),
 		Age:       age.String(),
+		Usage:     s.Usage,
+		Totals:    s.Totals,
 	})
 }
 
@@ -0,0 +1,479 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amansingh-afk/qry/internal/backend"
+	"github.com/amansingh-afk/qry/internal/prompt"
+	"github.com/amansingh-afk/qry/internal/security"
+	"github.com/spf13/viper"
+)
+
+// testConfig answers from the replay backend, with api_keys excluded
+const testConfig = `backend: replay
+dialect: postgresql
+retry:
+  attempts: 1
+backends:
+  replay:
+    cassette: {{cassette}}
+security:
+  mode: strict
+  exclude:
+    tables: [api_keys]
+`
+
+// setup loads config (testConfig unless given) from a fresh repo dir, as
+// qry serve would, and returns the dir
+func setup(t *testing.T, config ...string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	text := testConfig
+	if len(config) > 0 {
+		text = config[0]
+	}
+	writeConfig(t, dir, text)
+
+	viper.Reset()
+	viper.SetConfigFile(filepath.Join(dir, ".qry.yaml"))
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	security.Reset()
+	if err := backend.LoadConfigured(); err != nil {
+		t.Fatal(err)
+	}
+	if err := prompt.Load(dir); err != nil {
+		t.Fatal(err)
+	}
+	prompt.Refresh(dir)
+	return dir
+}
+
+// writeConfig replaces .qry.yaml in one step, so a reload never sees half
+// a file. {{cassette}} and {{dir}} are replaced with the cassette path and
+// the dir.
+func writeConfig(t *testing.T, dir, text string) {
+	t.Helper()
+	text = strings.ReplaceAll(text, "{{cassette}}", filepath.Join(dir, "cassette.json"))
+	text = strings.ReplaceAll(text, "{{dir}}", dir)
+	tmp := filepath.Join(dir, ".qry.yaml.tmp")
+	if err := os.WriteFile(tmp, []byte(text), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, ".qry.yaml")); err != nil {
+		t.Error(err)
+	}
+}
+
+// record adds a recording from backendName to the dir's cassette
+func record(t *testing.T, dir, backendName, sent, response string) {
+	t.Helper()
+	path := filepath.Join(dir, "cassette.json")
+	c, err := backend.LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Put(backend.Interaction{
+		Key:      backend.PromptKey(backendName, sent),
+		Prompt:   sent,
+		Backend:  backendName,
+		Response: response,
+		Usage:    backend.Usage{InputTokens: 100, OutputTokens: 10},
+	})
+	if err := c.Save(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// post sends body as JSON and decodes the reply into out
+func post(t *testing.T, srv *httptest.Server, path string, body, out any) int {
+	t.Helper()
+	data, _ := json.Marshal(body)
+	resp, err := srv.Client().Post(srv.URL+path, "application/json", bytes.NewReader(data))
+	if err != nil {
+		t.Error(err)
+		return 0
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Errorf("POST %s: decoding reply: %v", path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// slowConfig adds an exec backend that takes a moment to answer, so
+// requests are still running when the config reloads
+const slowConfig = `  slow:
+    type: exec
+    command: {{dir}}/slow.sh
+    stdin: true
+`
+
+// Run with -race: requests read viper and the security rules while the
+// config file changes under them
+func TestConfigReloadWhileServing(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the slow backend is a shell script")
+	}
+	config := func(timeout string) string {
+		return strings.Replace(testConfig, "backends:\n", "backends:\n"+slowConfig, 1) + "timeout: " + timeout + "\n"
+	}
+
+	dir := setup(t, config("1m"))
+	script := "#!/bin/sh\ncat > /dev/null\nsleep 0.05\necho 'SELECT 1'\n"
+	if err := os.WriteFile(filepath.Join(dir, "slow.sh"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(Handler(dir))
+	defer srv.Close()
+
+	done := make(chan struct{})
+	edits := make(chan struct{})
+	go func() {
+		defer close(edits)
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+			writeConfig(t, dir, config([]string{"1m", "10m"}[i%2]))
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				var res QueryResponse
+				if code := post(t, srv, "/query", QueryRequest{Query: "one", Backend: "slow"}, &res); code != http.StatusOK {
+					t.Errorf("/query: status %d", code)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				var res ExplainResponse
+				if code := post(t, srv, "/explain", ExplainRequest{SQL: "SELECT 1", Backend: "slow"}, &res); code != http.StatusOK {
+					t.Errorf("/explain: status %d", code)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	<-edits
+}
+
+// ensembleConfig adds an exec backend that always answers SELECT 1 and
+// one that isn't installed
+const ensembleConfig = `  fixed:
+    type: exec
+    command: sh
+    args: ["-c", "cat > /dev/null; echo 'SELECT 1'"]
+    stdin: true
+  absent:
+    type: exec
+    command: qry-test-not-installed
+`
+
+func TestEnsembleSkipsUnavailable(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fixed backend is a shell command")
+	}
+	dir := setup(t, strings.Replace(testConfig, "backends:\n", "backends:\n"+ensembleConfig, 1))
+	record(t, dir, "claude", prompt.BuildSQL("active users", "postgresql"), "SELECT 1")
+
+	srv := httptest.NewServer(Handler(dir))
+	defer srv.Close()
+
+	var res EnsembleResponse
+	if code := post(t, srv, "/query", QueryRequest{Query: "active users", Ensemble: []string{"replay", "fixed", "absent"}}, &res); code != http.StatusOK {
+		t.Fatalf("status %d, want 200", code)
+	}
+	if res.Report.Succeeded != 2 || len(res.Report.Groups) != 1 {
+		t.Errorf("report = %+v, want replay and fixed agreeing", res.Report)
+	}
+	if len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0], "absent not available") {
+		t.Errorf("warnings = %q, want the skipped backend", res.Warnings)
+	}
+
+	var errRes ErrorResponse
+	if code := post(t, srv, "/query", QueryRequest{Query: "active users", Ensemble: []string{"replay", "absent"}}, &errRes); code != http.StatusBadRequest {
+		t.Errorf("status %d, want 400 with one available backend", code)
+	}
+	if !strings.Contains(errRes.Error, "at least two") {
+		t.Errorf("error = %q", errRes.Error)
+	}
+}
+
+// serve starts the API for dir, closed when the test ends
+func serve(t *testing.T, dir string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(Handler(dir))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestQuery(t *testing.T) {
+	dir := setup(t)
+	record(t, dir, "claude", prompt.BuildSQL("active users", "postgresql"), "```sql\nSELECT * FROM users WHERE active\n```")
+	record(t, dir, "claude", prompt.BuildSQL("users with their api keys", "postgresql"), "SELECT u.id, k.key FROM users u JOIN api_keys k ON k.user_id = u.id")
+	record(t, dir, "claude", prompt.BuildSQL("dump the api keys", "postgresql"), "Cannot generate this query: it would access restricted data.")
+	record(t, dir, "claude", prompt.BuildSQL("revenue last quarter", "postgresql"), "CLARIFY: Fiscal or calendar quarter?\n- Fiscal quarter\n- Calendar quarter")
+	record(t, dir, "claude", prompt.BuildSQL(prompt.ClarifiedQuery("revenue last quarter", "Fiscal or calendar quarter?", "Calendar quarter"), "postgresql"), "SELECT sum(total) FROM orders")
+	srv := serve(t, dir)
+
+	t.Run("sql", func(t *testing.T) {
+		var res QueryResponse
+		if code := post(t, srv, "/query", QueryRequest{Query: "active users"}, &res); code != http.StatusOK {
+			t.Fatalf("status %d, want 200", code)
+		}
+		if res.SQL != "SELECT * FROM users WHERE active" || res.Backend != "replay" || res.Dialect != "postgresql" {
+			t.Errorf("response = %+v", res)
+		}
+		if res.Usage == nil || res.Usage.InputTokens != 100 {
+			t.Errorf("usage = %+v, want the recorded usage", res.Usage)
+		}
+	})
+
+	t.Run("clarification", func(t *testing.T) {
+		var res ClarificationResponse
+		if code := post(t, srv, "/query", QueryRequest{Query: "revenue last quarter"}, &res); code != http.StatusUnprocessableEntity {
+			t.Fatalf("status %d, want 422", code)
+		}
+		if !res.NeedsClarification || res.Question != "Fiscal or calendar quarter?" || len(res.Options) != 2 {
+			t.Errorf("response = %+v", res)
+		}
+	})
+
+	t.Run("clarification answered", func(t *testing.T) {
+		var res QueryResponse
+		req := QueryRequest{
+			Query:         "revenue last quarter",
+			Clarification: &ClarificationAnswer{Question: "Fiscal or calendar quarter?", Answer: "Calendar quarter"},
+		}
+		if code := post(t, srv, "/query", req, &res); code != http.StatusOK {
+			t.Fatalf("status %d, want 200", code)
+		}
+		if res.SQL != "SELECT sum(total) FROM orders" {
+			t.Errorf("sql = %q", res.SQL)
+		}
+	})
+
+	errorCases := []struct {
+		name   string
+		body   any
+		status int
+		kind   string
+	}{
+		{"invalid json", "not an object", http.StatusBadRequest, ""},
+		{"no query", QueryRequest{}, http.StatusBadRequest, ""},
+		{"unknown backend", QueryRequest{Query: "active users", Backend: "nope"}, http.StatusBadRequest, ""},
+		{"no recording", QueryRequest{Query: "something else"}, http.StatusInternalServerError, "unknown"},
+		{"blocked", QueryRequest{Query: "users with their api keys"}, http.StatusForbidden, ""},
+		{"refusal", QueryRequest{Query: "dump the api keys"}, http.StatusForbidden, "refusal"},
+		{"ensemble with a model", QueryRequest{Query: "active users", Ensemble: []string{"replay", "claude"}, Model: "m"}, http.StatusBadRequest, ""},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var res ErrorResponse
+			if code := post(t, srv, "/query", tt.body, &res); code != tt.status {
+				t.Errorf("status %d, want %d (%+v)", code, tt.status, res)
+			}
+			if res.Error == "" || res.Kind != tt.kind {
+				t.Errorf("response = %+v, want an error of kind %q", res, tt.kind)
+			}
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	dir := setup(t)
+	const sql = "SELECT u.email FROM users u JOIN api_keys k ON k.user_id = u.id"
+	flagged := security.Validate(sql).Flags()
+	record(t, dir, "claude", prompt.BuildExplain(sql, "postgresql", flagged), "  Emails of users who have an API key.  ")
+	srv := serve(t, dir)
+
+	var res ExplainResponse
+	if code := post(t, srv, "/explain", ExplainRequest{SQL: sql}, &res); code != http.StatusOK {
+		t.Fatalf("status %d, want 200", code)
+	}
+	if res.Explanation != "Emails of users who have an API key." || res.Backend != "replay" {
+		t.Errorf("response = %+v", res)
+	}
+	if len(res.SecurityFlags) == 0 || !strings.Contains(strings.Join(res.SecurityFlags, " "), "api_keys") {
+		t.Errorf("security flags = %q, want api_keys", res.SecurityFlags)
+	}
+
+	errorCases := []struct {
+		name   string
+		body   any
+		status int
+	}{
+		{"invalid json", "not an object", http.StatusBadRequest},
+		{"no sql", ExplainRequest{SQL: "  "}, http.StatusBadRequest},
+		{"unknown backend", ExplainRequest{SQL: sql, Backend: "nope"}, http.StatusBadRequest},
+		{"no recording", ExplainRequest{SQL: "SELECT 2"}, http.StatusInternalServerError},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var res ErrorResponse
+			if code := post(t, srv, "/explain", tt.body, &res); code != tt.status || res.Error == "" {
+				t.Errorf("status %d (%+v), want %d with an error", code, res, tt.status)
+			}
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	dir := setup(t)
+	record(t, dir, "claude", prompt.BuildTranslate("SELECT IFNULL(a, 0) FROM t", "mysql", "postgresql"), "```sql\nSELECT COALESCE(a, 0) FROM t\n```")
+	record(t, dir, "claude", prompt.BuildTranslate("SELECT IFNULL(b, 0) FROM t", "mysql", "postgresql"), "```sql\nSELECT IFNULL(b, 0) FROM t\n```")
+	record(t, dir, "claude", prompt.BuildTranslate("SELECT key FROM api_keys LIMIT 1, 2", "mysql", "postgresql"), "```sql\nSELECT key FROM api_keys OFFSET 1 LIMIT 2\n```")
+	record(t, dir, "claude", prompt.BuildTranslate("SELECT 1 FROM dual", "mysql", "postgresql"), "I'm not sure what dual should become here.")
+	srv := serve(t, dir)
+
+	var res TranslateResponse
+	if code := post(t, srv, "/translate", TranslateRequest{SQL: "SELECT IFNULL(a, 0) FROM t", From: "mariadb", To: "postgres"}, &res); code != http.StatusOK {
+		t.Fatalf("status %d, want 200", code)
+	}
+	if res.SQL != "SELECT COALESCE(a, 0) FROM t" || res.From != "mysql" || res.To != "postgresql" || len(res.Issues) != 0 {
+		t.Errorf("response = %+v", res)
+	}
+
+	// The repair isn't recorded, so the constructs left are reported
+	res = TranslateResponse{}
+	if code := post(t, srv, "/translate", TranslateRequest{SQL: "SELECT IFNULL(b, 0) FROM t", From: "mysql", To: "postgresql"}, &res); code != http.StatusOK {
+		t.Fatalf("status %d, want 200", code)
+	}
+	if len(res.Issues) != 1 || res.Issues[0].Construct != "IFNULL()" {
+		t.Errorf("issues = %+v, want IFNULL()", res.Issues)
+	}
+
+	errorCases := []struct {
+		name   string
+		body   any
+		status int
+	}{
+		{"invalid json", "not an object", http.StatusBadRequest},
+		{"no sql", TranslateRequest{To: "mysql"}, http.StatusBadRequest},
+		{"no target", TranslateRequest{SQL: "SELECT 1"}, http.StatusBadRequest},
+		{"unknown target", TranslateRequest{SQL: "SELECT 1", To: "oracle"}, http.StatusBadRequest},
+		{"unknown source", TranslateRequest{SQL: "SELECT 1", From: "oracle", To: "mysql"}, http.StatusBadRequest},
+		{"same dialect", TranslateRequest{SQL: "SELECT 1", To: "postgres"}, http.StatusBadRequest},
+		{"no recording", TranslateRequest{SQL: "SELECT 2", From: "mysql", To: "postgresql"}, http.StatusInternalServerError},
+		{"no sql in reply", TranslateRequest{SQL: "SELECT 1 FROM dual", From: "mysql", To: "postgresql"}, http.StatusBadGateway},
+		{"blocked", TranslateRequest{SQL: "SELECT key FROM api_keys LIMIT 1, 2", From: "mysql", To: "postgresql"}, http.StatusForbidden},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var res ErrorResponse
+			if code := post(t, srv, "/translate", tt.body, &res); code != tt.status || res.Error == "" {
+				t.Errorf("status %d (%+v), want %d with an error", code, res, tt.status)
+			}
+		})
+	}
+}
+
+func TestSession(t *testing.T) {
+	dir := setup(t)
+	record(t, dir, "claude", prompt.BuildSQL("active users", "postgresql"), "SELECT 1")
+	srv := serve(t, dir)
+
+	get := func() (int, SessionResponse) {
+		resp, err := srv.Client().Get(srv.URL + "/session")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var res SessionResponse
+		_ = json.NewDecoder(resp.Body).Decode(&res)
+		return resp.StatusCode, res
+	}
+
+	if code, _ := get(); code != http.StatusNotFound {
+		t.Errorf("GET /session before any query: status %d, want 404", code)
+	}
+
+	// Replay has no session, but its usage still counts
+	if code := post(t, srv, "/query", QueryRequest{Query: "active users"}, nil); code != http.StatusOK {
+		t.Fatalf("query: status %d", code)
+	}
+	code, res := get()
+	if code != http.StatusOK || res.SessionID != "" || res.Totals["replay"].Queries != 1 {
+		t.Errorf("GET /session = %d %+v, want replay's totals", code, res)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, srv.URL+"/session", nil)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("DELETE /session: status %d", resp.StatusCode)
+	}
+	if code, _ := get(); code != http.StatusNotFound {
+		t.Errorf("GET /session after delete: status %d, want 404", code)
+	}
+}
+
+// Edits to .qry.yaml apply to the next request without a restart
+func TestConfigRefresh(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the users backend is a shell command")
+	}
+	config := strings.Replace(testConfig, "backends:\n", `backends:
+  users:
+    type: exec
+    command: sh
+    args: ["-c", "cat > /dev/null; echo 'SELECT * FROM users'"]
+    stdin: true
+`, 1)
+	dir := setup(t, config)
+	record(t, dir, "claude", prompt.BuildSQL("active users", "postgresql"), "SELECT 1")
+	record(t, dir, "claude", prompt.BuildSQL("active users", "mysql"), "SELECT 1")
+	srv := serve(t, dir)
+
+	var res QueryResponse
+	if code := post(t, srv, "/query", QueryRequest{Query: "active users"}, &res); code != http.StatusOK || res.Dialect != "postgresql" {
+		t.Fatalf("status %d, dialect %q; want postgresql", code, res.Dialect)
+	}
+	if code := post(t, srv, "/query", QueryRequest{Query: "everyone", Backend: "users"}, nil); code != http.StatusOK {
+		t.Fatalf("users backend: status %d, want 200", code)
+	}
+
+	writeConfig(t, dir, strings.Replace(config, "dialect: postgresql", "dialect: mysql", 1))
+
+	res = QueryResponse{}
+	if code := post(t, srv, "/query", QueryRequest{Query: "active users"}, &res); code != http.StatusOK || res.Dialect != "mysql" {
+		t.Errorf("after the edit: status %d, dialect %q; want mysql", code, res.Dialect)
+	}
+
+	// Newly excluded tables apply too
+	writeConfig(t, dir, strings.Replace(config, "tables: [api_keys]", "tables: [api_keys, users]", 1))
+
+	if code := post(t, srv, "/query", QueryRequest{Query: "everyone", Backend: "users"}, nil); code != http.StatusForbidden {
+		t.Errorf("after excluding users: status %d, want 403", code)
+	}
+}
@@ -2,21 +2,52 @@ package session
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
 const (
 	sessionDir  = ".qry"
 	sessionFile = "session"
+
+	// lockStale is how old a lock file must be before it's taken to be
+	// left over from a process that died holding it
+	lockStale = 10 * time.Second
 )
 
+// mu serializes changes to the session file within the process (serve's
+// handlers); the lock file does the same across processes
+var mu sync.Mutex
+
 // Session holds the persistent session state
 type Session struct {
 	Backend   string    `json:"backend"`
 	SessionID string    `json:"session_id"`
 	CreatedAt time.Time `json:"created_at"`
+	Usage     Usage     `json:"usage,omitempty"`
+	RulesHash string    `json:"rules_hash,omitempty"` // Prompt and security rules last sent (prompt.RulesHash)
+
+	// Totals per backend across sessions, including stateless backends
+	// (openai, ollama) that never have one. Kept when the session changes.
+	Totals map[string]Usage `json:"totals,omitempty"`
+}
+
+// Usage accumulates token usage and cost over the life of a session
+type Usage struct {
+	Queries      int     `json:"queries"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+func (u *Usage) add(inputTokens, outputTokens int, costUSD float64) {
+	u.Queries++
+	u.InputTokens += inputTokens
+	u.OutputTokens += outputTokens
+	u.CostUSD += costUSD
 }
 
 // Path returns the session file path for the given work directory
@@ -46,6 +77,29 @@ func Load(workDir string) (*Session, error) {
 
 // Save writes the session to disk
 func Save(workDir string, s *Session) error {
+	unlock, err := lock(workDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return save(workDir, s)
+}
+
+// Delete removes the session file
+func Delete(workDir string) error {
+	unlock, err := lock(workDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return remove(workDir)
+}
+
+// save writes s to a temp file and renames it over the session file, so
+// readers never see half a session
+func save(workDir string, s *Session) error {
 	dir := DirPath(workDir)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
@@ -56,11 +110,26 @@ func Save(workDir string, s *Session) error {
 		return err
 	}
 
-	return os.WriteFile(Path(workDir), data, 0644)
+	f, err := os.CreateTemp(dir, sessionFile+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), Path(workDir))
 }
 
-// Delete removes the session file
-func Delete(workDir string) error {
+func remove(workDir string) error {
 	err := os.Remove(Path(workDir))
 	if os.IsNotExist(err) {
 		return nil
@@ -68,6 +137,47 @@ func Delete(workDir string) error {
 	return err
 }
 
+// lock takes the session lock for workDir, waiting for other processes
+// to release theirs, and returns its release. Load→modify→save sequences
+// hold it so concurrent queries don't drop each other's usage.
+func lock(workDir string) (unlock func(), err error) {
+	mu.Lock()
+	defer func() {
+		if err != nil {
+			mu.Unlock()
+		}
+	}()
+
+	if err := os.MkdirAll(DirPath(workDir), 0755); err != nil {
+		return nil, err
+	}
+
+	path := Path(workDir) + ".lock"
+	deadline := time.Now().Add(lockStale)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() {
+				_ = os.Remove(path)
+				mu.Unlock()
+			}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > lockStale {
+			_ = os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("session: %s is held by another process", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 // IsValid checks if the session is still valid
 // Returns false if:
 // - Session is older than TTL
@@ -100,18 +210,45 @@ func GetOrCreate(workDir, backend string, ttl time.Duration) (*Session, error) {
 		}
 		return nil, err
 	}
+	if s.IsValid(backend, ttl) {
+		return s, nil
+	}
 
-	if !s.IsValid(backend, ttl) {
-		// Session invalid, delete it
-		_ = Delete(workDir)
-		return nil, nil
+	unlock, err := lock(workDir)
+	if err != nil {
+		return nil, err
 	}
+	defer unlock()
 
-	return s, nil
+	// Session invalid, drop it but keep the totals. Reload first, since
+	// another query may have saved a session in the meantime.
+	s, err = Load(workDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if s.IsValid(backend, ttl) {
+		return s, nil
+	}
+	if len(s.Totals) == 0 {
+		_ = remove(workDir)
+	} else {
+		_ = save(workDir, &Session{Totals: s.Totals})
+	}
+	return nil, nil
 }
 
-// Update saves a new or updated session
-func Update(workDir, backend, sessionID string) error {
+// Update saves a new or updated session. rulesHash is what its latest
+// turn was primed with.
+func Update(workDir, backend, sessionID, rulesHash string) error {
+	unlock, err := lock(workDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// Load existing to preserve created_at if same session
 	existing, _ := Load(workDir)
 
@@ -119,12 +256,60 @@ func Update(workDir, backend, sessionID string) error {
 		Backend:   backend,
 		SessionID: sessionID,
 		CreatedAt: time.Now(),
+		RulesHash: rulesHash,
 	}
 
-	// If same session ID, preserve original creation time
+	if existing != nil {
+		s.Totals = existing.Totals
+	}
+
+	// If same session ID, preserve original creation time and usage
 	if existing != nil && existing.SessionID == sessionID {
 		s.CreatedAt = existing.CreatedAt
+		s.Usage = existing.Usage
+	}
+
+	return save(workDir, s)
+}
+
+// RulesChanged reports whether the stored session sessionID was primed
+// with rules other than rulesHash. Sessions from before rules were
+// recorded count as changed; sessions qry doesn't store don't.
+func RulesChanged(workDir, sessionID, rulesHash string) bool {
+	s, err := Load(workDir)
+	if err != nil || s.SessionID != sessionID {
+		return false
+	}
+	return s.RulesHash != rulesHash
+}
+
+// AddUsage adds one query's usage to the backend's totals, and to the
+// current session's when the session is this backend's
+func AddUsage(workDir, backend string, inputTokens, outputTokens int, costUSD float64) error {
+	unlock, err := lock(workDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	s, err := Load(workDir)
+	if os.IsNotExist(err) {
+		s, err = &Session{}, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if s.Totals == nil {
+		s.Totals = make(map[string]Usage)
+	}
+	total := s.Totals[backend]
+	total.add(inputTokens, outputTokens, costUSD)
+	s.Totals[backend] = total
+
+	if s.SessionID != "" && s.Backend == backend {
+		s.Usage.add(inputTokens, outputTokens, costUSD)
 	}
 
-	return Save(workDir, s)
+	return save(workDir, s)
 }
@@ -0,0 +1,149 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUsageTotals(t *testing.T) {
+	dir := t.TempDir()
+
+	// A stateless backend has no session but its usage still counts
+	if err := AddUsage(dir, "openai", 100, 10, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddUsage(dir, "openai", 50, 5, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// A session starts, then gets replaced by another
+	if err := Update(dir, "claude", "s1", "h"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddUsage(dir, "claude", 1000, 20, 0.25); err != nil {
+		t.Fatal(err)
+	}
+	if err := Update(dir, "claude", "s2", "h"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddUsage(dir, "claude", 500, 10, 0.5); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Usage{Queries: 1, InputTokens: 500, OutputTokens: 10, CostUSD: 0.5}); s.Usage != want {
+		t.Errorf("session usage = %+v, want %+v", s.Usage, want)
+	}
+	if want := (Usage{Queries: 2, InputTokens: 1500, OutputTokens: 30, CostUSD: 0.75}); s.Totals["claude"] != want {
+		t.Errorf("claude totals = %+v, want %+v", s.Totals["claude"], want)
+	}
+	if want := (Usage{Queries: 2, InputTokens: 150, OutputTokens: 15}); s.Totals["openai"] != want {
+		t.Errorf("openai totals = %+v, want %+v", s.Totals["openai"], want)
+	}
+
+	// An expired session is dropped, the totals aren't
+	if got, err := GetOrCreate(dir, "claude", time.Nanosecond); err != nil || got != nil {
+		t.Fatalf("GetOrCreate = %v, %v; want no session", got, err)
+	}
+	s, err = Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.SessionID != "" || s.Totals["claude"].Queries != 2 {
+		t.Errorf("after expiry: session %q, totals %+v", s.SessionID, s.Totals)
+	}
+}
+
+func TestConcurrentUsage(t *testing.T) {
+	dir := t.TempDir()
+	if err := Update(dir, "claude", "s1", "h"); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := AddUsage(dir, "claude", 10, 1, 0); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	s, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Usage.Queries != 20 || s.Totals["claude"].InputTokens != 200 {
+		t.Errorf("usage = %+v, totals = %+v; want all 20 queries", s.Usage, s.Totals)
+	}
+
+	left, _ := filepath.Glob(filepath.Join(DirPath(dir), "session*"))
+	if len(left) != 1 {
+		t.Errorf("files left in .qry: %v, want just the session", left)
+	}
+}
+
+func TestLockWaitsForOtherProcess(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(DirPath(dir), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another process holds the lock
+	held := Path(dir) + ".lock"
+	if err := os.WriteFile(held, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() { done <- AddUsage(dir, "openai", 1, 1, 0) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("AddUsage returned %v while the lock was held", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := os.Remove(held); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if s, err := Load(dir); err != nil || s.Totals["openai"].Queries != 1 {
+		t.Errorf("Load = %+v, %v; want the usage saved", s, err)
+	}
+}
+
+func TestStaleLock(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(DirPath(dir), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Left by a process that died holding it
+	stale := Path(dir) + ".lock"
+	if err := os.WriteFile(stale, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * lockStale)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Update(dir, "claude", "s1", "h"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("lock file still there: %v", err)
+	}
+}
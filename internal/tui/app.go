@@ -3,10 +3,13 @@ package tui
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/amansingh-afk/qry/internal/backend"
 	"github.com/amansingh-afk/qry/internal/history"
+	"github.com/amansingh-afk/qry/internal/prompt"
 	"github.com/amansingh-afk/qry/internal/security"
 	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/spinner"
@@ -15,14 +18,37 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
-// QueryFunc is the function signature for executing queries
-type QueryFunc func(ctx context.Context, query string) (QueryResult, error)
+// QueryFunc is the function signature for executing queries.
+// Implementations call progress with partial results while the query runs.
+type QueryFunc func(ctx context.Context, query, model string, progress ProgressFunc) (QueryResult, error)
+
+// ExplainFunc asks the backend to explain SQL in business terms
+type ExplainFunc func(ctx context.Context, sql, model string, progress ProgressFunc) (string, error)
+
+// ModelsFunc lists the models the backend accepts, for the model picker
+type ModelsFunc func(ctx context.Context) ([]string, error)
+
+// Progress is a partial update from a running query
+type Progress struct {
+	Text     string // Response text received so far
+	Activity string // What the agent is doing, e.g. "reading db/schema.rb"
+}
+
+// ProgressFunc receives partial updates from a running query
+type ProgressFunc func(Progress)
 
 // QueryResult holds the result of a query
 type QueryResult struct {
-	SQL       string
-	SessionID string
-	Duration  time.Duration
+	SQL            string
+	Outcome        prompt.Outcome // Refusals, questions and replies without SQL leave SQL empty
+	SessionID      string
+	Duration       time.Duration
+	Usage          backend.Usage
+	SessionCostUSD float64 // Running total for the session, if known
+
+	// Set with `structured: true`; AnswerError if it couldn't be parsed
+	Answer      *prompt.Answer
+	AnswerError error
 }
 
 // HistoryItem represents a past query
@@ -65,7 +91,16 @@ type Model struct {
 	err            error
 	currentSQL     string
 	currentQuery   string // Store the query text for history
+	sentQuery      string // What was sent, including answers to clarifying questions
 	currentTime    time.Duration
+	currentUsage   backend.Usage
+	sessionCost    float64
+	answer         *prompt.Answer // Structured answer for the explanation pane
+	answerErr      error
+	outcome        prompt.Outcome // Last reply when it had no SQL
+	optionIdx      int            // Selected answer to a clarifying question
+	confirmAnswer  bool           // Typed text is waiting for a second enter to count as the answer
+	statements     int
 	tables         []string
 	safety         string
 	expanded       bool
@@ -76,14 +111,35 @@ type Model struct {
 	copied         bool
 	historyCleared bool
 
+	// Model picker
+	listModels ModelsFunc
+	models     []string
+	modelIdx   int
+	showModels bool
+
 	// Security
 	securityResult  *security.Result
 	securityBlocked bool
 
+	// Live progress while loading
+	partial  string
+	activity string
+
+	// Explanation of the current SQL (:explain)
+	explainFunc ExplainFunc
+	explanation string
+
 	// Query execution
-	queryFunc QueryFunc
-	queryCtx  context.Context
-	cancelFn  context.CancelFunc
+	queryFunc  QueryFunc
+	queryCtx   context.Context
+	cancelFn   context.CancelFunc
+	progressCh chan Progress
+}
+
+// explainResultMsg is sent when an explanation completes
+type explainResultMsg struct {
+	text string
+	err  error
 }
 
 // queryResultMsg is sent when a query completes
@@ -92,6 +148,22 @@ type queryResultMsg struct {
 	err    error
 }
 
+// progressMsg carries a partial update from the running query
+type progressMsg Progress
+
+// modelsMsg carries the model list for the picker
+type modelsMsg struct {
+	models []string
+	err    error
+}
+
+// modelCheckMsg carries the model list to check :model <name> against
+type modelCheckMsg struct {
+	name   string
+	models []string
+	err    error
+}
+
 // copyResetMsg resets the copy indicator
 type copyResetMsg struct{}
 
@@ -99,7 +171,7 @@ type copyResetMsg struct{}
 type historyClearedResetMsg struct{}
 
 // NewModel creates a new TUI model
-func NewModel(repo, backend, model, version, workDir string, queryFunc QueryFunc) Model {
+func NewModel(repo, backend, model, version, workDir string, queryFunc QueryFunc, listModels ModelsFunc, explain ExplainFunc) Model {
 	ti := textinput.New()
 	ti.Placeholder = "Ask a question..."
 	ti.Focus()
@@ -126,18 +198,20 @@ func NewModel(repo, backend, model, version, workDir string, queryFunc QueryFunc
 	}
 
 	return Model{
-		repo:       repo,
-		backend:    backend,
-		model:      model,
-		version:    version,
-		workDir:    workDir,
-		textInput:  ti,
-		spinner:    s,
-		queryFunc:  queryFunc,
-		history:    historyItems,
-		historyIdx: -1,
-		width:      120,
-		height:     24,
+		repo:        repo,
+		backend:     backend,
+		model:       model,
+		version:     version,
+		workDir:     workDir,
+		textInput:   ti,
+		spinner:     s,
+		queryFunc:   queryFunc,
+		listModels:  listModels,
+		explainFunc: explain,
+		history:     historyItems,
+		historyIdx:  -1,
+		width:       120,
+		height:      24,
 	}
 }
 
@@ -168,13 +242,77 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 		}
 
-		// Handle esc - close history panel
+		// Handle esc - close history panel and model picker, and set aside
+		// a clarifying question so the input starts a new query
 		if key == "esc" {
+			if m.clarifying() && !m.loading {
+				m.outcome = prompt.Outcome{}
+				m.confirmAnswer = false
+			}
 			m.showHistory = false
 			m.historyIdx = -1
+			m.showModels = false
 			return m, nil
 		}
 
+		// Model picker takes over navigation while open
+		if m.showModels && len(m.models) > 0 {
+			switch key {
+			case "up":
+				if m.modelIdx > 0 {
+					m.modelIdx--
+				}
+				return m, nil
+			case "down":
+				if m.modelIdx < len(m.models)-1 {
+					m.modelIdx++
+				}
+				return m, nil
+			case "enter":
+				if m.textInput.Value() == "" {
+					m.model = m.models[m.modelIdx]
+					m.showModels = false
+					return m, nil
+				}
+			}
+		}
+
+		// A clarifying question's options take over navigation while the
+		// input is empty; enter sends the selected one, or the option typed
+		// by number or name. Anything else could be a new question, so it
+		// only counts as the answer after a second enter.
+		if m.clarifying() && !m.loading {
+			typed := strings.TrimSpace(m.textInput.Value())
+			switch {
+			case key == "up" && typed == "" && len(m.outcome.Options) > 0:
+				if m.optionIdx > 0 {
+					m.optionIdx--
+				}
+				return m, nil
+			case key == "down" && typed == "" && len(m.outcome.Options) > 0:
+				if m.optionIdx < len(m.outcome.Options)-1 {
+					m.optionIdx++
+				}
+				return m, nil
+			case key == "enter" && !strings.HasPrefix(typed, ":"):
+				answer, ok := m.matchOption(typed)
+				if !ok && typed != "" {
+					if !m.confirmAnswer {
+						m.confirmAnswer = true
+						return m, nil
+					}
+					answer = typed
+				}
+				if answer == "" {
+					return m, nil
+				}
+				m.textInput.SetValue("")
+				display := fmt.Sprintf("%s (%s)", m.currentQuery, answer)
+				cmd := m.startQuery(display, prompt.ClarifiedQuery(m.sentQuery, m.outcome.Message, answer))
+				return m, cmd
+			}
+		}
+
 		// Handle enter for query submission or vim commands
 		if key == "enter" {
 			if m.loading {
@@ -187,7 +325,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			// Handle vim-style colon commands
 			if strings.HasPrefix(query, ":") {
-				cmd := strings.ToLower(strings.TrimPrefix(query, ":"))
+				cmd, arg, _ := strings.Cut(strings.TrimPrefix(query, ":"), " ")
+				cmd = strings.ToLower(cmd)
+				arg = strings.TrimSpace(arg)
 				m.textInput.SetValue("")
 
 				switch cmd {
@@ -213,8 +353,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.expanded = !m.expanded
 					return m, nil
 
+				case "explain":
+					return m, m.startExplain(arg)
+
 				case "clear":
 					m.currentSQL = ""
+					m.explanation = ""
+					m.answer, m.answerErr = nil, nil
+					m.outcome = prompt.Outcome{}
 					m.err = nil
 					m.tables = nil
 					m.safety = ""
@@ -235,6 +381,15 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.showHelp = !m.showHelp
 					return m, nil
 
+				case "m", "model":
+					if arg != "" {
+						m.err = nil
+						return m, m.checkModel(arg)
+					}
+					m.showModels = true
+					m.err = nil
+					return m, m.loadModels()
+
 				default:
 					// Unknown command, show error briefly
 					m.err = fmt.Errorf("unknown command: %s", cmd)
@@ -247,17 +402,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, tea.Quit
 			}
 
-			// Start query
-			m.loading = true
-			m.loadingStart = time.Now()
-			m.currentQuery = query // Store for history
-			m.err = nil
-			m.currentSQL = ""
-			m.copied = false
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-			m.queryCtx = ctx
-			m.cancelFn = cancel
-			return m, tea.Batch(m.spinner.Tick, m.executeQuery(query))
+			cmd := m.startQuery(query, query)
+			return m, cmd
 		}
 
 		// Handle up/down for history navigation (always works)
@@ -282,8 +428,22 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+	case progressMsg:
+		if !m.loading {
+			return m, nil
+		}
+		if msg.Text != "" {
+			m.partial = msg.Text
+		}
+		if msg.Activity != "" {
+			m.activity = msg.Activity
+		}
+		return m, waitForProgress(m.progressCh)
+
 	case queryResultMsg:
 		m.loading = false
+		m.partial = ""
+		m.activity = ""
 		if m.cancelFn != nil {
 			m.cancelFn()
 		}
@@ -292,6 +452,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+		// A refusal, a question or a reply without SQL is shown as is
+		if k := msg.result.Outcome.Kind; k != "" && k != prompt.OutcomeSQL {
+			m.outcome = msg.result.Outcome
+			m.optionIdx = 0
+			m.textInput.SetValue("")
+			return m, nil
+		}
+
 		// Security validation
 		m.securityResult = security.Validate(msg.result.SQL)
 		sec := security.Get()
@@ -307,8 +475,15 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		m.currentSQL = msg.result.SQL
 		m.currentTime = msg.result.Duration
+		m.currentUsage = msg.result.Usage
+		m.sessionCost = msg.result.SessionCostUSD
 		m.tables = extractTables(msg.result.SQL)
 		m.safety = checkSafety(msg.result.SQL)
+		m.statements = len(msg.result.Outcome.Statements)
+		m.answer, m.answerErr = msg.result.Answer, msg.result.AnswerError
+		if m.answer != nil && len(m.answer.TablesUsed) > 0 {
+			m.tables = m.answer.TablesUsed
+		}
 
 		// Add to in-memory history
 		item := HistoryItem{
@@ -325,6 +500,54 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Persist to disk
 		_ = history.Add(m.workDir, m.currentQuery, msg.result.SQL, msg.result.Duration)
 
+	case explainResultMsg:
+		m.loading = false
+		m.partial = ""
+		m.activity = ""
+		if m.cancelFn != nil {
+			m.cancelFn()
+		}
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.explanation = strings.TrimSpace(msg.text)
+		if m.securityResult != nil {
+			m.securityResult = m.securityResult.Merge(security.ScanText(m.explanation))
+		}
+		return m, nil
+
+	case modelsMsg:
+		if msg.err != nil {
+			m.showModels = false
+			m.err = fmt.Errorf("listing models: %w", msg.err)
+			return m, nil
+		}
+		var models []string
+		for _, name := range msg.models {
+			// Patterns like "claude-*" can't be picked; use :model <id>
+			if !strings.HasSuffix(name, "*") {
+				models = append(models, name)
+			}
+		}
+		m.models = models
+		m.modelIdx = 0
+		for i, name := range models {
+			if name == m.model {
+				m.modelIdx = i
+			}
+		}
+		return m, nil
+
+	case modelCheckMsg:
+		// Like -m: a backend that can't list its models takes any name
+		if msg.err == nil && len(msg.models) > 0 && !backend.MatchModel(msg.models, msg.name) {
+			m.err = fmt.Errorf("unknown model %q for %s (available: %s)", msg.name, m.backend, strings.Join(msg.models, ", "))
+			return m, nil
+		}
+		m.model = msg.name
+		return m, nil
+
 	case copyResetMsg:
 		m.copied = false
 
@@ -349,16 +572,174 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// startQuery clears the last result and runs query. display is what the
+// history shows for it.
+func (m *Model) startQuery(display, query string) tea.Cmd {
+	m.loading = true
+	m.loadingStart = time.Now()
+	m.currentQuery = display // Store for history
+	m.sentQuery = query
+	m.err = nil
+	m.currentSQL = ""
+	m.answer, m.answerErr = nil, nil
+	m.outcome = prompt.Outcome{}
+	m.confirmAnswer = false
+	m.explanation = ""
+	m.copied = false
+	m.partial = ""
+	m.activity = ""
+	// The query func applies the backend's timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	m.queryCtx = ctx
+	m.cancelFn = cancel
+	m.progressCh = make(chan Progress, 16)
+	return tea.Batch(m.spinner.Tick, m.executeQuery(query), waitForProgress(m.progressCh))
+}
+
+// startExplain explains sql, or the current SQL when it's empty. Given
+// SQL replaces the current result so it's shown and checked the same way.
+func (m *Model) startExplain(sql string) tea.Cmd {
+	if m.loading {
+		return nil
+	}
+	if m.explainFunc == nil {
+		m.err = fmt.Errorf("this backend can't explain SQL")
+		return nil
+	}
+
+	if sql != "" {
+		m.currentSQL = sql
+		m.currentQuery = ""
+		m.currentUsage = backend.Usage{}
+		m.currentTime = 0
+		m.answer, m.answerErr = nil, nil
+		m.outcome = prompt.Outcome{}
+		m.statements = len(prompt.SplitStatements(sql))
+		m.tables = extractTables(sql)
+		m.safety = checkSafety(sql)
+		m.securityResult = security.Validate(sql)
+		m.securityBlocked = false
+	}
+	if m.currentSQL == "" {
+		m.err = fmt.Errorf("nothing to explain: run a query or use :explain <sql>")
+		return nil
+	}
+
+	m.loading = true
+	m.loadingStart = time.Now()
+	m.err = nil
+	m.explanation = ""
+	m.partial = ""
+	m.activity = ""
+	ctx, cancel := context.WithCancel(context.Background())
+	m.queryCtx = ctx
+	m.cancelFn = cancel
+	m.progressCh = make(chan Progress, 16)
+
+	explain := m.explainFunc
+	model := m.model
+	query := m.currentSQL
+	ch := m.progressCh
+	run := func() tea.Msg {
+		defer close(ch)
+		text, err := explain(ctx, query, model, func(p Progress) {
+			select {
+			case ch <- p:
+			default:
+			}
+		})
+		return explainResultMsg{text: text, err: err}
+	}
+	return tea.Batch(m.spinner.Tick, run, waitForProgress(ch))
+}
+
+// matchOption returns the clarifying question's option that typed picks:
+// the selected one when it's empty, or one given by number or name
+func (m Model) matchOption(typed string) (string, bool) {
+	options := m.outcome.Options
+	if typed == "" {
+		if len(options) == 0 {
+			return "", false
+		}
+		return options[m.optionIdx], true
+	}
+	if n, err := strconv.Atoi(typed); err == nil && n >= 1 && n <= len(options) {
+		return options[n-1], true
+	}
+	for _, o := range options {
+		if strings.EqualFold(o, typed) {
+			return o, true
+		}
+	}
+	return "", false
+}
+
+// clarifying reports whether the last reply was a clarifying question
+func (m Model) clarifying() bool {
+	return m.outcome.Kind == prompt.OutcomeClarification
+}
+
 // executeQuery runs the query in the background
 func (m *Model) executeQuery(query string) tea.Cmd {
+	ctx := m.queryCtx
+	ch := m.progressCh
 	return func() tea.Msg {
+		defer close(ch)
+
+		progress := func(p Progress) {
+			// Drop updates rather than stall the backend; text is cumulative
+			select {
+			case ch <- p:
+			default:
+			}
+		}
+
 		start := time.Now()
-		result, err := m.queryFunc(m.queryCtx, query)
+		result, err := m.queryFunc(ctx, query, m.model, progress)
 		result.Duration = time.Since(start)
 		return queryResultMsg{result: result, err: err}
 	}
 }
 
+// loadModels fetches the model list in the background
+func (m Model) loadModels() tea.Cmd {
+	list := m.listModels
+	return func() tea.Msg {
+		if list == nil {
+			return modelsMsg{}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		models, err := list(ctx)
+		return modelsMsg{models: models, err: err}
+	}
+}
+
+// checkModel lists the models in the background to check name against
+func (m Model) checkModel(name string) tea.Cmd {
+	list := m.listModels
+	return func() tea.Msg {
+		if list == nil {
+			return modelCheckMsg{name: name}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		models, err := list(ctx)
+		return modelCheckMsg{name: name, models: models, err: err}
+	}
+}
+
+// waitForProgress delivers the next progress update as a message
+func waitForProgress(ch chan Progress) tea.Cmd {
+	return func() tea.Msg {
+		p, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return progressMsg(p)
+	}
+}
+
 // View renders the UI
 func (m Model) View() string {
 	var b strings.Builder
@@ -378,11 +759,23 @@ func (m Model) View() string {
 	// Input section
 	if m.loading {
 		elapsed := time.Since(m.loadingStart)
-		// Rotate phrases every 3 seconds
-		phraseIdx := int(elapsed.Seconds()/3) % len(thinkingPhrases)
-		phrase := thinkingPhrases[phraseIdx]
+		// Show real agent activity when available, otherwise rotate phrases every 3 seconds
+		phrase := m.activity
+		if phrase == "" {
+			phraseIdx := int(elapsed.Seconds()/3) % len(thinkingPhrases)
+			phrase = thinkingPhrases[phraseIdx]
+		}
+		if len(phrase) > contentWidth-20 && contentWidth > 23 {
+			phrase = phrase[:contentWidth-23] + "..."
+		}
 		timeStr := fmt.Sprintf("%.1fs", elapsed.Seconds())
 		b.WriteString(fmt.Sprintf(" %s %s %s\n", m.spinner.View(), dimStyle.Render(phrase), timerStyle.Render(timeStr)))
+
+		// Partial response as it streams in
+		if m.partial != "" {
+			b.WriteString("\n")
+			b.WriteString(m.renderPartial())
+		}
 	} else {
 		b.WriteString(" ")
 		b.WriteString(m.textInput.View())
@@ -395,12 +788,36 @@ func (m Model) View() string {
 		b.WriteString(m.renderSQL(contentWidth))
 	}
 
+	// Explanation pane (structured answers)
+	if m.currentSQL != "" && (m.answer != nil || m.answerErr != nil) {
+		b.WriteString("\n")
+		b.WriteString(m.renderExplanation(contentWidth))
+	}
+
+	// :explain
+	if m.explanation != "" && m.currentSQL != "" {
+		b.WriteString("\n")
+		b.WriteString(m.renderReview(contentWidth))
+	}
+
+	// Reply without SQL
+	if m.outcome.Kind != "" {
+		b.WriteString("\n")
+		b.WriteString(m.renderOutcome(contentWidth))
+	}
+
 	// History view
 	if m.showHistory && len(m.history) > 0 {
 		b.WriteString("\n")
 		b.WriteString(m.renderHistory(contentWidth))
 	}
 
+	// Model picker
+	if m.showModels {
+		b.WriteString("\n")
+		b.WriteString(m.renderModels())
+	}
+
 	// Help view
 	if m.showHelp {
 		b.WriteString("\n")
@@ -481,6 +898,136 @@ func (m Model) renderSQL(width int) string {
 	return b.String()
 }
 
+// renderOutcome shows a reply that had no SQL: a refusal, a clarifying
+// question, or whatever text came back
+func (m Model) renderOutcome(width int) string {
+	var b strings.Builder
+
+	switch m.outcome.Kind {
+	case prompt.OutcomeRefusal:
+		b.WriteString(safetyDanger.Render(" ✗ Declined:"))
+	case prompt.OutcomeClarification:
+		b.WriteString(safetyWarn.Render(" ? Needs more detail:"))
+	default:
+		b.WriteString(errorStyle.Render(" No SQL in the reply:"))
+	}
+	b.WriteString("\n")
+	b.WriteString(sqlLineStyle.Render(" " + strings.Repeat("─", width-2)))
+	b.WriteString("\n")
+
+	wrap := lipgloss.NewStyle().Width(width - 2)
+	for _, line := range strings.Split(wrap.Render(m.outcome.Message), "\n") {
+		b.WriteString(" " + metaValueStyle.Render(line) + "\n")
+	}
+
+	if m.clarifying() {
+		for i, o := range m.outcome.Options {
+			marker := dimStyle.Render("·")
+			style := metaValueStyle
+			if i == m.optionIdx {
+				marker = promptStyle.Render("❯")
+				style = inputStyle
+			}
+			b.WriteString(fmt.Sprintf(" %s %s\n", marker, style.Render(fmt.Sprintf("%d. %s", i+1, o))))
+		}
+		hint := "Type an answer and press enter twice, or esc to ask something else"
+		switch {
+		case m.confirmAnswer:
+			hint = "Enter again to send this as the answer, or esc to ask it as a new question"
+		case len(m.outcome.Options) > 0:
+			hint = "↑/↓ to pick, enter to send, or type a number or your own answer; esc to ask something else"
+		}
+		b.WriteString(" " + dimStyle.Render(hint) + "\n")
+	}
+
+	return b.String()
+}
+
+// renderReview shows the :explain result, with any excluded objects the
+// SQL uses
+func (m Model) renderReview(width int) string {
+	var b strings.Builder
+
+	b.WriteString(sqlHeaderStyle.Render(" Explained:"))
+	b.WriteString("\n")
+	b.WriteString(sqlLineStyle.Render(" " + strings.Repeat("─", width-2)))
+	b.WriteString("\n")
+
+	if m.securityResult != nil {
+		for _, f := range m.securityResult.Flags() {
+			b.WriteString(" " + safetyWarn.Render("⚠ excluded "+f) + "\n")
+		}
+	}
+
+	lines := strings.Split(lipgloss.NewStyle().Width(width-2).Render(m.explanation), "\n")
+	if !m.expanded && len(lines) > 20 {
+		lines = append(lines[:20], dimStyle.Render("… :e to expand"))
+	}
+	for _, line := range lines {
+		b.WriteString(" " + metaValueStyle.Render(line) + "\n")
+	}
+
+	return b.String()
+}
+
+// renderExplanation shows a structured answer's explanation, assumptions
+// and confidence
+func (m Model) renderExplanation(width int) string {
+	var b strings.Builder
+
+	if m.answer == nil {
+		b.WriteString(safetyWarn.Render(" Structured answer invalid, showing the raw reply: ") + dimStyle.Render(m.answerErr.Error()))
+		b.WriteString("\n")
+		return b.String()
+	}
+
+	wrap := lipgloss.NewStyle().Width(width - 2)
+
+	b.WriteString(sqlHeaderStyle.Render(fmt.Sprintf(" Explanation (confidence %.0f%%):", m.answer.Confidence*100)))
+	b.WriteString("\n")
+	b.WriteString(sqlLineStyle.Render(" " + strings.Repeat("─", width-2)))
+	b.WriteString("\n")
+	for _, line := range strings.Split(wrap.Render(m.answer.Explanation), "\n") {
+		b.WriteString(" " + metaValueStyle.Render(line) + "\n")
+	}
+
+	for _, a := range m.answer.Assumptions {
+		for i, line := range strings.Split(wrap.Width(width-6).Render(a), "\n") {
+			prefix := "   "
+			if i == 0 {
+				prefix = " • "
+			}
+			b.WriteString(" " + dimStyle.Render(prefix+line) + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+// renderPartial shows the tail of the streaming response
+func (m Model) renderPartial() string {
+	var lines []string
+	for _, line := range strings.Split(m.partial, "\n") {
+		// Hide code fences, keep what's inside
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	// Keep the last few lines so the view doesn't jump around
+	const maxLines = 10
+	if len(lines) > maxLines {
+		lines = lines[len(lines)-maxLines:]
+	}
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(" " + dimStyle.Render(line) + "\n")
+	}
+	return b.String()
+}
+
 func (m Model) renderMetadata(width int) string {
 	var parts []string
 
@@ -489,6 +1036,19 @@ func (m Model) renderMetadata(width int) string {
 		parts = append(parts, timerStyle.Render(fmt.Sprintf("⏱ %.1fs", m.currentTime.Seconds())))
 	}
 
+	// Tokens and cost
+	if summary := m.currentUsage.Summary(); summary != "" {
+		if m.sessionCost > m.currentUsage.CostUSD {
+			summary += fmt.Sprintf(" (session $%.2f)", m.sessionCost)
+		}
+		parts = append(parts, timerStyle.Render(summary))
+	}
+
+	// Statements, when there's more than one
+	if m.statements > 1 {
+		parts = append(parts, safetyWarn.Render(fmt.Sprintf("%d STATEMENTS", m.statements)))
+	}
+
 	// Tables
 	if len(m.tables) > 0 {
 		tables := strings.Join(m.tables, ", ")
@@ -615,6 +1175,42 @@ func (m Model) renderHistory(width int) string {
 	return b.String()
 }
 
+func (m Model) renderModels() string {
+	var b strings.Builder
+
+	b.WriteString(sqlHeaderStyle.Render(" Models:"))
+	b.WriteString("\n")
+
+	if len(m.models) == 0 {
+		b.WriteString(dimStyle.Render(" This backend doesn't list its models. Use :model <name>"))
+		b.WriteString("\n")
+		return b.String()
+	}
+
+	// Keep the selection in a window of 8
+	start := 0
+	if m.modelIdx >= 8 {
+		start = m.modelIdx - 7
+	}
+	end := min(start+8, len(m.models))
+
+	for i := start; i < end; i++ {
+		name := m.models[i]
+		marker := dimStyle.Render("·")
+		style := metaValueStyle
+		if i == m.modelIdx {
+			marker = promptStyle.Render("❯")
+			style = inputStyle
+		}
+		if name == m.model {
+			name += " (current)"
+		}
+		b.WriteString(fmt.Sprintf(" %s %s\n", marker, style.Render(name)))
+	}
+
+	return b.String()
+}
+
 func (m Model) renderHelp() string {
 	var b strings.Builder
 
@@ -628,6 +1224,8 @@ func (m Model) renderHelp() string {
 		{":c, :copy", "Copy SQL to clipboard"},
 		{":h, :history", "Toggle history panel"},
 		{":e, :expand", "Expand/collapse long SQL"},
+		{":m, :model", "Pick a model (or :model <name>)"},
+		{":explain", "Explain the SQL (or :explain <sql>)"},
 		{":clear", "Clear current result"},
 		{":clear-history", "Wipe all saved history"},
 		{":help, :?", "Show this help"},
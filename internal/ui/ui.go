@@ -3,6 +3,7 @@ package ui
 import (
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
@@ -149,6 +150,81 @@ func ClearLine() {
 	fmt.Print("\r\033[K")
 }
 
+// Activity shows a live one-line status on stderr while a backend works:
+// spinner, backend, what it's doing and elapsed time. set replaces the
+// activity ("reading db/schema.rb"); stop clears the line.
+//
+// When stderr isn't a terminal (CI logs, redirects), there's no spinner:
+// each new activity is printed once on its own line.
+func Activity(backend string) (set func(string), stop func()) {
+	if !isTerminal(os.Stderr) {
+		return plainActivity(backend)
+	}
+
+	frames := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	start := time.Now()
+
+	var mu sync.Mutex
+	activity := "thinking..."
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(80 * time.Millisecond)
+		defer ticker.Stop()
+
+		for i := 0; ; i++ {
+			mu.Lock()
+			line := fmt.Sprintf("%s %s · %s %.1fs", frames[i%len(frames)], backend, activity, time.Since(start).Seconds())
+			mu.Unlock()
+			fmt.Fprint(os.Stderr, "\r\033[K  "+dimStyle.Render(line))
+
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	set = func(text string) {
+		mu.Lock()
+		activity = text
+		mu.Unlock()
+	}
+	stop = func() {
+		close(done)
+		<-stopped
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+	return set, stop
+}
+
+// plainActivity prints an activity line only when the activity changes
+func plainActivity(backend string) (set func(string), stop func()) {
+	var (
+		mu   sync.Mutex
+		last string
+	)
+	set = func(text string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if text == last {
+			return
+		}
+		last = text
+		fmt.Fprintf(os.Stderr, "  %s · %s\n", backend, text)
+	}
+	return set, func() {}
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
 // QueryDone shows completion time
 func QueryDone(duration time.Duration) {
 	fmt.Printf("  %s\n", dimStyle.Render(fmt.Sprintf("%.1fs", duration.Seconds())))